@@ -3,6 +3,7 @@ module github.com/go-hep/examples
 go 1.13
 
 require (
+	github.com/apache/arrow/go/arrow v0.0.0-20200228160020-c67ff099122d
 	github.com/pkg/profile v1.4.0
 	go-hep.org/x/hep v0.24.1
 	golang.org/x/exp v0.0.0-20200228211341-fcea875c7e85
@@ -0,0 +1,20 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("01-arrow", "plots the MET of all events (rarrow)", func() Analysis { return newArrow(&arrow1{}) })
+}
+
+// arrow1 plots the MET in an event.
+type arrow1 struct {
+	basic1
+}
+
+func (ana *arrow1) ProcessFrame(f *frame) error {
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	fill(ana.hmet, f.Float32s("MET_sumet"), nil)
+	return nil
+}
@@ -5,67 +5,51 @@
 package main
 
 import (
-	"fmt"
-
-	"go-hep.org/x/hep/groot"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/plot/vg"
 )
 
-// basic1 plots the MET in an event.
-func basic1(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
-
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
-	}
-
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
-
-	var (
-		hmet = hbook.NewH1D(100, 0, 2000)
-	)
+func init() {
+	register("01-basic", "plots the MET of all events", func() Analysis { return &basic1{} })
+}
 
-	sc, err := rtree.NewTreeScannerVars(tree, rtree.ScanVar{Name: "MET_sumet"})
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
-	}
-	defer sc.Close()
+// basic1 plots the MET in an event.
+type basic1 struct {
+	met float32
 
-	for sc.Next() {
-		var met float32
-		err := sc.Scan(&met)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
+	hmet *hbook.H1D
+	cuts *CutFlow
+}
 
-		hmet.Fill(float64(met), 1)
+func (ana *basic1) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "MET_sumet", Value: &ana.met},
 	}
+}
 
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
-	}
+func (ana *basic1) Book() []hbook.Histogram {
+	ana.hmet = newH1D("hmet", 100, 0, 2000)
+	ana.cuts = newCutFlow("all events")
+	return []hbook.Histogram{ana.hmet}
+}
 
-	fmt.Printf("hmet: %v\n", hmet.SumW())
+func (ana *basic1) Process() error {
+	ana.cuts.Fill(0, 1)
+	ana.hmet.Fill(float64(ana.met), 1)
+	return nil
+}
 
+func (ana *basic1) Plot() Plotter {
 	p := hplot.New()
 	p.X.Label.Text = "MET [GeV]"
 	p.Y.Label.Text = "Nevts"
 
-	p.Add(hplot.NewH1D(hmet))
+	p.Add(hplot.NewH1D(ana.hmet))
 
-	err = p.Save(10*vg.Centimeter, -1, "01-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return p
+}
 
-	return nil
+func (ana *basic1) CutFlow() *CutFlow {
+	return ana.cuts
 }
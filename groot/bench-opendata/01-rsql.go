@@ -5,50 +5,101 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
-	"go-hep.org/x/hep/groot"
+	"github.com/go-hep/examples/groot/progress"
 	"go-hep.org/x/hep/groot/rsql"
 	_ "go-hep.org/x/hep/groot/rsql/rsqldrv"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/plot/vg"
 )
 
+func init() {
+	register("01-rsql", "plots the MET of all events (rsql)", func() Analysis { return &rsql1{} })
+}
+
 // rsql1 plots the MET in an event.
-func rsql1(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
+type rsql1 struct {
+	hmet *hbook.H1D
+	cuts *CutFlow
+}
 
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
-	}
+func (ana *rsql1) Vars() []rtree.ScanVar { return nil }
+
+func (ana *rsql1) Book() []hbook.Histogram {
+	ana.hmet = newH1D("hmet", 100, 0, 2000)
+	ana.cuts = newCutFlow("all events")
+	return []hbook.Histogram{ana.hmet}
+}
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
+func (ana *rsql1) Process() error {
+	return fmt.Errorf("rsql1 can only process whole trees")
+}
 
-	hmet, err := rsql.ScanH1D(tree, "SELECT MET_sumet FROM Events", hbook.NewH1D(100, 0, 2000))
+// ProcessTree fills the histogram from the whole tree with rsql.ScanH1D,
+// which can not be interrupted, or from the entries selected by the run
+// context with rsql.Scan, checking the context before each entry.
+func (ana *rsql1) ProcessTree(ctx context.Context, tree rtree.Tree, rctx RunContext) (int64, error) {
+	var (
+		err   error
+		nevts int64
+		rep   = progress.FromContext(ctx)
+	)
+	switch {
+	case rctx.IsAll():
+		_, err = rsql.ScanH1D(tree, "SELECT MET_sumet FROM Events", ana.hmet)
+		nevts = tree.Entries()
+		rep.Add(nevts)
+	default:
+		var (
+			n    = int64(0)
+			end  = rctx.End(tree.Entries())
+			stop = ctx.Done()
+		)
+		err = rsql.Scan(tree, "SELECT MET_sumet FROM Events", func(met float64) error {
+			select {
+			case <-stop:
+				return ctx.Err()
+			default:
+			}
+			i := n
+			n++
+			if i >= end {
+				return errStopScan
+			}
+			if rctx.Selects(i) {
+				ana.hmet.Fill(met, 1)
+				nevts++
+				rep.Add(1)
+			}
+			return nil
+		})
+		if err == errStopScan {
+			err = nil
+		}
+	}
+	ana.cuts.fillN(0, nevts, 1)
 	if err != nil {
-		return fmt.Errorf("could not scan tree: %w", err)
+		if ctx.Err() != nil {
+			return nevts, ctx.Err()
+		}
+		return nevts, fmt.Errorf("could not scan tree: %w", err)
 	}
+	return nevts, nil
+}
 
-	fmt.Printf("hmet: %v\n", hmet.SumW())
-
+func (ana *rsql1) Plot() Plotter {
 	p := hplot.New()
 	p.X.Label.Text = "MET [GeV]"
 	p.Y.Label.Text = "Nevts"
 
-	p.Add(hplot.NewH1D(hmet))
+	p.Add(hplot.NewH1D(ana.hmet))
 
-	err = p.Save(10*vg.Centimeter, -1, "01-rsql.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return p
+}
 
-	return nil
+func (ana *rsql1) CutFlow() *CutFlow {
+	return ana.cuts
 }
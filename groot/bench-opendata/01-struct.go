@@ -0,0 +1,56 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+)
+
+func init() {
+	register("01-struct", "plots the MET of all events (struct)", func() Analysis { return &struct1{} })
+}
+
+// struct1 plots the MET in an event.
+type struct1 struct {
+	evt Event
+
+	hmet *hbook.H1D
+	cuts *CutFlow
+}
+
+func (ana *struct1) Vars() []rtree.ScanVar {
+	return ana.evt.Vars("MET_sumet")
+}
+
+func (ana *struct1) Book() []hbook.Histogram {
+	ana.hmet = newH1D("hmet", 100, 0, 2000)
+	ana.cuts = newCutFlow("all events")
+	return []hbook.Histogram{ana.hmet}
+}
+
+func (ana *struct1) Process() error {
+	ana.cuts.Fill(0, 1)
+	evt := &ana.evt
+	evt.Load()
+
+	ana.hmet.Fill(float64(evt.MET.SumEt), 1)
+	return nil
+}
+
+func (ana *struct1) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "MET [GeV]"
+	p.Y.Label.Text = "Nevts"
+
+	p.Add(hplot.NewH1D(ana.hmet))
+
+	return p
+}
+
+func (ana *struct1) CutFlow() *CutFlow {
+	return ana.cuts
+}
@@ -0,0 +1,22 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("02-arrow", "plots the pT of all jets (rarrow)", func() Analysis { return newArrow(&arrow2{}) })
+}
+
+// arrow2 plots the Jet pT of all jets in an event.
+type arrow2 struct {
+	basic2
+}
+
+func (ana *arrow2) ProcessFrame(f *frame) error {
+	pt := f.Jagged("Jet_pt")
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	fillCut(ana.cuts, 1, atLeast(sizes(pt.offs), 1))
+	fill(ana.hJetPt, pt.vals, nil)
+	return nil
+}
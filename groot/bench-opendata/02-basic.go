@@ -5,68 +5,57 @@
 package main
 
 import (
-	"fmt"
-
-	"go-hep.org/x/hep/groot"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/plot/vg"
 )
 
-// basic2 plots the Jet pT of all jets in an event.
-func basic2(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
-
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
-	}
+func init() {
+	register("02-basic", "plots the pT of all jets", func() Analysis { return &basic2{} })
+}
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
+// basic2 plots the Jet pT of all jets in an event.
+type basic2 struct {
+	jetPt []float32
 
-	var (
-		hJetPt = hbook.NewH1D(100, 15, 60)
-	)
+	hJetPt *hbook.H1D
+	cuts   *CutFlow
+}
 
-	sc, err := rtree.NewTreeScannerVars(tree, rtree.ScanVar{Name: "Jet_pt"})
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
+func (ana *basic2) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "Jet_pt", Value: &ana.jetPt},
 	}
-	defer sc.Close()
+}
 
-	for sc.Next() {
-		var jetPt []float32
-		err := sc.Scan(&jetPt)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
-		for _, pt := range jetPt {
-			hJetPt.Fill(float64(pt), 1)
-		}
-	}
+func (ana *basic2) Book() []hbook.Histogram {
+	ana.hJetPt = newH1D("hJetPt", 100, 15, 60)
+	ana.cuts = newCutFlow("all events", ">= 1 jet")
+	return []hbook.Histogram{ana.hJetPt}
+}
 
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
+func (ana *basic2) Process() error {
+	ana.cuts.Fill(0, 1)
+	if len(ana.jetPt) > 0 {
+		ana.cuts.Fill(1, 1)
 	}
 
-	fmt.Printf("hJetPt: %v\n", hJetPt.SumW())
+	for _, pt := range ana.jetPt {
+		ana.hJetPt.Fill(float64(pt), 1)
+	}
+	return nil
+}
 
+func (ana *basic2) Plot() Plotter {
 	p := hplot.New()
 	p.X.Label.Text = "Jet Pt [GeV]"
 	p.Y.Label.Text = "Nevts"
 
-	p.Add(hplot.NewH1D(hJetPt))
+	p.Add(hplot.NewH1D(ana.hJetPt))
 
-	err = p.Save(10*vg.Centimeter, -1, "02-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return p
+}
 
-	return nil
+func (ana *basic2) CutFlow() *CutFlow {
+	return ana.cuts
 }
@@ -0,0 +1,18 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("02-rsql", "plots the pT of all jets (rsql)", rsql2)
+}
+
+// rsql2 plots the Jet pT of all jets in an event.
+//
+// SQL: the ">= 1 jet" cut (WHERE nJet >= 1).
+// Go:  the filling of the pT of each jet, as rsqldrv can not unroll jagged
+// columns into rows.
+func rsql2() Analysis {
+	return newRSQL(&basic2{}, "nJet >= 1")
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+)
+
+func init() {
+	register("02-struct", "plots the pT of all jets (struct)", func() Analysis { return &struct2{} })
+}
+
+// struct2 plots the Jet pT of all jets in an event.
+type struct2 struct {
+	evt Event
+
+	hJetPt *hbook.H1D
+	cuts   *CutFlow
+}
+
+func (ana *struct2) Vars() []rtree.ScanVar {
+	return ana.evt.Vars("Jet_pt")
+}
+
+func (ana *struct2) Book() []hbook.Histogram {
+	ana.hJetPt = newH1D("hJetPt", 100, 15, 60)
+	ana.cuts = newCutFlow("all events", ">= 1 jet")
+	return []hbook.Histogram{ana.hJetPt}
+}
+
+func (ana *struct2) Process() error {
+	evt := &ana.evt
+	evt.Load()
+
+	ana.cuts.Fill(0, 1)
+	if len(evt.Jets) > 0 {
+		ana.cuts.Fill(1, 1)
+	}
+
+	for _, jet := range evt.Jets {
+		ana.hJetPt.Fill(float64(jet.Pt), 1)
+	}
+	return nil
+}
+
+func (ana *struct2) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "Jet Pt [GeV]"
+	p.Y.Label.Text = "Nevts"
+
+	p.Add(hplot.NewH1D(ana.hJetPt))
+
+	return p
+}
+
+func (ana *struct2) CutFlow() *CutFlow {
+	return ana.cuts
+}
@@ -0,0 +1,26 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("03-arrow", "plots the pT of jets with |eta| < 1 (rarrow)", func() Analysis { return newArrow(&arrow3{}) })
+}
+
+// arrow3 plots the Jet pT with eta cuts on the jet.
+type arrow3 struct {
+	basic3
+}
+
+func (ana *arrow3) ProcessFrame(f *frame) error {
+	var (
+		pt  = f.Jagged("Jet_pt")
+		eta = f.Jagged("Jet_eta")
+		sel = absLt(eta.vals, 1)
+	)
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	fillCut(ana.cuts, 1, atLeast(count(pt.offs, sel), 1))
+	fill(ana.hJetPt, pt.vals, sel)
+	return nil
+}
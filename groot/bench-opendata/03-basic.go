@@ -5,77 +5,67 @@
 package main
 
 import (
-	"fmt"
 	"math"
 
-	"go-hep.org/x/hep/groot"
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/plot/vg"
 )
 
+func init() {
+	register("03-basic", "plots the pT of jets with |eta| < 1", func() Analysis { return &basic3{} })
+}
+
 // basic3 plots the Jet pT with eta cuts on the jet.
-func basic3(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
+type basic3 struct {
+	jetPt  []float32
+	jetEta []float32
+	jets   nanoaod.Jets
 
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
-	}
+	hJetPt *hbook.H1D
+	cuts   *CutFlow
+}
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
+func (ana *basic3) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "Jet_pt", Value: &ana.jetPt},
+		{Name: "Jet_eta", Value: &ana.jetEta},
+	}
+}
 
-	var (
-		hJetPt = hbook.NewH1D(100, 15, 60)
-	)
+func (ana *basic3) Book() []hbook.Histogram {
+	ana.hJetPt = newH1D("hJetPt", 100, 15, 60)
+	ana.cuts = newCutFlow("all events", ">= 1 jet with |eta| < 1")
+	return []hbook.Histogram{ana.hJetPt}
+}
 
-	sc, err := rtree.NewTreeScannerVars(tree,
-		rtree.ScanVar{Name: "Jet_pt"},
-		rtree.ScanVar{Name: "Jet_eta"},
-	)
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
-	}
-	defer sc.Close()
+func (ana *basic3) Process() error {
+	ana.cuts.Fill(0, 1)
 
-	for sc.Next() {
-		var (
-			jetPt  []float32
-			jetEta []float32
-		)
-		err := sc.Scan(&jetPt, &jetEta)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
-		for i, pt := range jetPt {
-			if math.Abs(float64(jetEta[i])) < 1 {
-				hJetPt.Fill(float64(pt), 1)
-			}
-		}
+	ana.jets.Load(ana.jetPt, ana.jetEta, nil, nil, nil)
+	ana.jets.Keep(func(jet *nanoaod.Jet) bool {
+		return math.Abs(float64(jet.Eta)) < 1
+	})
+	for _, jet := range ana.jets {
+		ana.hJetPt.Fill(float64(jet.Pt), 1)
 	}
-
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
+	if len(ana.jets) > 0 {
+		ana.cuts.Fill(1, 1)
 	}
+	return nil
+}
 
-	fmt.Printf("hJetPt: %v\n", hJetPt.SumW())
-
+func (ana *basic3) Plot() Plotter {
 	p := hplot.New()
 	p.X.Label.Text = "Jet Pt [GeV]"
 	p.Y.Label.Text = "Nevts"
 
-	p.Add(hplot.NewH1D(hJetPt))
+	p.Add(hplot.NewH1D(ana.hJetPt))
 
-	err = p.Save(10*vg.Centimeter, -1, "03-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return p
+}
 
-	return nil
+func (ana *basic3) CutFlow() *CutFlow {
+	return ana.cuts
 }
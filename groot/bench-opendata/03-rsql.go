@@ -0,0 +1,18 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("03-rsql", "plots the pT of jets with |eta| < 1 (rsql)", rsql3)
+}
+
+// rsql3 plots the Jet pT with eta cuts on the jet.
+//
+// SQL: the pre-selection of events with at least 1 jet (WHERE nJet >= 1).
+// Go:  the |eta| < 1 cut on each jet, as rsqldrv can not apply cuts to the
+// elements of jagged columns.
+func rsql3() Analysis {
+	return newRSQL(&basic3{}, "nJet >= 1")
+}
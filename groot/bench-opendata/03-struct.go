@@ -0,0 +1,68 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+)
+
+func init() {
+	register("03-struct", "plots the pT of jets with |eta| < 1 (struct)", func() Analysis { return &struct3{} })
+}
+
+// struct3 plots the Jet pT with eta cuts on the jet.
+type struct3 struct {
+	evt Event
+
+	hJetPt *hbook.H1D
+	cuts   *CutFlow
+}
+
+func (ana *struct3) Vars() []rtree.ScanVar {
+	return ana.evt.Vars("Jet_pt", "Jet_eta")
+}
+
+func (ana *struct3) Book() []hbook.Histogram {
+	ana.hJetPt = newH1D("hJetPt", 100, 15, 60)
+	ana.cuts = newCutFlow("all events", ">= 1 jet with |eta| < 1")
+	return []hbook.Histogram{ana.hJetPt}
+}
+
+func (ana *struct3) Process() error {
+	evt := &ana.evt
+	evt.Load()
+
+	ana.cuts.Fill(0, 1)
+
+	evt.Jets.Keep(func(jet *nanoaod.Jet) bool {
+		return math.Abs(float64(jet.Eta)) < 1
+	})
+	for _, jet := range evt.Jets {
+		ana.hJetPt.Fill(float64(jet.Pt), 1)
+	}
+	if len(evt.Jets) > 0 {
+		ana.cuts.Fill(1, 1)
+	}
+	return nil
+}
+
+func (ana *struct3) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "Jet Pt [GeV]"
+	p.Y.Label.Text = "Nevts"
+
+	p.Add(hplot.NewH1D(ana.hJetPt))
+
+	return p
+}
+
+func (ana *struct3) CutFlow() *CutFlow {
+	return ana.cuts
+}
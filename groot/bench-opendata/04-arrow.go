@@ -0,0 +1,26 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("04-arrow", "plots the MET of events with "+spec4.String()+" (rarrow)", func() Analysis { return newArrow(&arrow4{}) })
+}
+
+// arrow4 plots the missing ET of events passing the spec4 selection.
+type arrow4 struct {
+	basic4
+}
+
+func (ana *arrow4) ProcessFrame(f *frame) error {
+	var (
+		pt   = f.Jagged("Jet_pt")
+		jets = gt(pt.vals, spec4.JetPt)
+		evts = atLeast(count(pt.offs, jets), spec4.NJets)
+	)
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	fillCut(ana.cuts, 1, evts)
+	fill(ana.hmet, f.Float32s("MET_pt"), evts)
+	return nil
+}
@@ -6,86 +6,90 @@ package main
 
 import (
 	"fmt"
-	"math"
 
-	"go-hep.org/x/hep/groot"
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/plot/vg"
 )
 
-// basic4 plots the missing ET of events with at least 2 jets above 40 GeV.
-func basic4(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
+func init() {
+	register("04-basic", "plots the MET of events with "+spec4.String(), func() Analysis { return &basic4{} })
+}
 
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
-	}
+// spec4 is the event selection of the 04 analyses, from the ADL benchmark 4:
+// "plot the MET of events that have at least two jets with pT > 40 GeV".
+var spec4 = selection4{
+	NJets: 2,
+	JetPt: 40,
+}
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
+// selection4 selects events with at least NJets jets with pT > JetPt.
+type selection4 struct {
+	NJets int     // minimum number of selected jets
+	JetPt float32 // minimum pT of the selected jets, in GeV
+}
 
-	var (
-		hmet = hbook.NewH1D(100, 0, 2000)
-	)
+// String returns the description of the selection.
+func (sel selection4) String() string {
+	return fmt.Sprintf("at least %d jets above %v GeV", sel.NJets, sel.JetPt)
+}
 
-	sc, err := rtree.NewTreeScannerVars(tree,
-		rtree.ScanVar{Name: "Jet_pt"},
-		rtree.ScanVar{Name: "Jet_eta"},
-		rtree.ScanVar{Name: "MET_sumet"},
-	)
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
-	}
-	defer sc.Close()
-
-	for sc.Next() {
-		var (
-			jetPt  []float32
-			jetEta []float32
-			met    float32
-		)
-		err := sc.Scan(&jetPt, &jetEta, &met)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
-		njets := 0
-	loop:
-		for i, pt := range jetPt {
-			if pt > 40 && math.Abs(float64(jetEta[i])) < 1 {
-				njets++
-				if njets > 1 {
-					break loop
-				}
-			}
-		}
-		if njets >= 2 {
-			hmet.Fill(float64(met), 1)
-		}
+// cuts returns the names of the selections in the cut-flow.
+func (sel selection4) cuts() []string {
+	return []string{
+		"all events",
+		fmt.Sprintf(">= %d jets with pT > %v GeV", sel.NJets, sel.JetPt),
 	}
+}
+
+// basic4 plots the missing ET of events passing the spec4 selection.
+type basic4 struct {
+	jetPt []float32
+	met   float32
+	jets  nanoaod.Jets
+
+	hmet *hbook.H1D
+	cuts *CutFlow
+}
 
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
+func (ana *basic4) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "Jet_pt", Value: &ana.jetPt},
+		{Name: "MET_pt", Value: &ana.met},
 	}
+}
+
+func (ana *basic4) Book() []hbook.Histogram {
+	ana.hmet = newH1D("hmet", 100, 0, 2000)
+	ana.cuts = newCutFlow(spec4.cuts()...)
+	return []hbook.Histogram{ana.hmet}
+}
 
-	fmt.Printf("hmet: %v\n", hmet.SumW())
+func (ana *basic4) Process() error {
+	ana.cuts.Fill(0, 1)
+
+	ana.jets.Load(ana.jetPt, nil, nil, nil, nil)
+	n := ana.jets.Count(func(jet *nanoaod.Jet) bool {
+		return jet.Pt > spec4.JetPt
+	})
+	if n >= spec4.NJets {
+		ana.cuts.Fill(1, 1)
+		ana.hmet.Fill(float64(ana.met), 1)
+	}
+	return nil
+}
 
+func (ana *basic4) Plot() Plotter {
 	p := hplot.New()
 	p.X.Label.Text = "MET [GeV]"
 	p.Y.Label.Text = "Nevts"
 
-	p.Add(hplot.NewH1D(hmet))
+	p.Add(hplot.NewH1D(ana.hmet))
 
-	err = p.Save(10*vg.Centimeter, -1, "04-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return p
+}
 
-	return nil
+func (ana *basic4) CutFlow() *CutFlow {
+	return ana.cuts
 }
@@ -0,0 +1,20 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "fmt"
+
+func init() {
+	register("04-rsql", "plots the MET of events with "+spec4.String()+" (rsql)", rsql4)
+}
+
+// rsql4 plots the missing ET of events passing the spec4 selection.
+//
+// SQL: the pre-selection of events with at least spec4.NJets jets
+// (WHERE nJet >= NJets).
+// Go:  the pT cut on each jet.
+func rsql4() Analysis {
+	return newRSQL(&basic4{}, fmt.Sprintf("nJet >= %d", spec4.NJets))
+}
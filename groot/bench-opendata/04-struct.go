@@ -0,0 +1,64 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+)
+
+func init() {
+	register("04-struct", "plots the MET of events with "+spec4.String()+" (struct)", func() Analysis { return &struct4{} })
+}
+
+// struct4 plots the missing ET of events passing the spec4 selection.
+type struct4 struct {
+	evt Event
+
+	hmet *hbook.H1D
+	cuts *CutFlow
+}
+
+func (ana *struct4) Vars() []rtree.ScanVar {
+	return ana.evt.Vars("Jet_pt", "MET_pt")
+}
+
+func (ana *struct4) Book() []hbook.Histogram {
+	ana.hmet = newH1D("hmet", 100, 0, 2000)
+	ana.cuts = newCutFlow(spec4.cuts()...)
+	return []hbook.Histogram{ana.hmet}
+}
+
+func (ana *struct4) Process() error {
+	evt := &ana.evt
+	evt.Load()
+
+	ana.cuts.Fill(0, 1)
+
+	n := evt.Jets.Count(func(jet *nanoaod.Jet) bool {
+		return jet.Pt > spec4.JetPt
+	})
+	if n >= spec4.NJets {
+		ana.cuts.Fill(1, 1)
+		ana.hmet.Fill(float64(evt.MET.Pt), 1)
+	}
+	return nil
+}
+
+func (ana *struct4) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "MET [GeV]"
+	p.Y.Label.Text = "Nevts"
+
+	p.Add(hplot.NewH1D(ana.hmet))
+
+	return p
+}
+
+func (ana *struct4) CutFlow() *CutFlow {
+	return ana.cuts
+}
@@ -0,0 +1,35 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("05-arrow", "plots the MET of events with "+spec5.String()+" (rarrow)", func() Analysis { return newArrow(&arrow5{}) })
+}
+
+// arrow5 plots the MEt for events passing the spec5 selection.
+type arrow5 struct {
+	basic5
+}
+
+func (ana *arrow5) ProcessFrame(f *frame) error {
+	var (
+		pt     = f.Jagged("Muon_pt")
+		eta    = f.Jagged("Muon_eta")
+		phi    = f.Jagged("Muon_phi")
+		mass   = f.Jagged("Muon_mass")
+		charge = f.JaggedI32("Muon_charge")
+
+		pairs = combinations(pt.offs, 2)
+		mll   = pairs.Mass(pt.vals, eta.vals, phi.vals, mass.vals)
+		os    = pairs.OppositeCharge(charge.vals)
+		evts  = pairs.Any(and(os, within(mll, spec5.MassLo, spec5.MassHi)))
+	)
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	fillCut(ana.cuts, 1, atLeast(sizes(pt.offs), spec5.NMuons))
+	fillCut(ana.cuts, 2, pairs.Any(os))
+	fillCut(ana.cuts, 3, evts)
+	fill(ana.hmet, f.Float32s("MET_pt"), evts)
+	return nil
+}
@@ -7,108 +7,132 @@ package main
 import (
 	"fmt"
 
-	"go-hep.org/x/hep/fmom"
-	"go-hep.org/x/hep/groot"
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/gonum/stat/combin"
-	"gonum.org/v1/plot/vg"
 )
 
-// basic5 plots the MEt for events that have an opposite-sign muon pair of mass 60-120 GeV.
-func basic5(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
+func init() {
+	register("05-basic", "plots the MET of events with "+spec5.String(), func() Analysis { return &basic5{} })
+}
+
+// spec5 is the event selection of the 05 analyses, from the ADL benchmark 5:
+// "plot the MET of events that have an opposite-charge muon pair with an
+// invariant mass between 60 and 120 GeV".
+var spec5 = selection5{
+	NMuons: 2,
+	MassLo: 60,
+	MassHi: 120,
+}
+
+// selection5 selects events with at least NMuons muons, among which an
+// opposite-sign pair with an invariant mass in the open interval
+// (MassLo, MassHi).
+type selection5 struct {
+	NMuons int     // minimum number of muons
+	MassLo float64 // lower bound of the mass window of the pair, in GeV
+	MassHi float64 // upper bound of the mass window of the pair, in GeV
+}
+
+// String returns the description of the selection.
+func (sel selection5) String() string {
+	return fmt.Sprintf("an opposite-sign muon pair of mass %v-%v GeV", sel.MassLo, sel.MassHi)
+}
+
+// inWindow returns whether the mass is in the window of the selection.
+func (sel selection5) inWindow(mass float64) bool {
+	return sel.MassLo < mass && mass < sel.MassHi
+}
+
+// cuts returns the names of the selections in the cut-flow.
+func (sel selection5) cuts() []string {
+	return []string{
+		"all events",
+		fmt.Sprintf(">= %d muons", sel.NMuons),
+		"opposite-sign muon pair",
+		fmt.Sprintf("%v < m(mu,mu) < %v GeV", sel.MassLo, sel.MassHi),
 	}
-	defer f.Close()
+}
 
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
+// basic5 plots the MEt for events passing the spec5 selection.
+type basic5 struct {
+	muPt     []float32
+	muEta    []float32
+	muPhi    []float32
+	muMass   []float32
+	muCharge []int32
+	met      float32
+	muons    nanoaod.Muons
+
+	hmet *hbook.H1D
+	cuts *CutFlow
+}
+
+func (ana *basic5) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "Muon_pt", Value: &ana.muPt},
+		{Name: "Muon_eta", Value: &ana.muEta},
+		{Name: "Muon_phi", Value: &ana.muPhi},
+		{Name: "Muon_mass", Value: &ana.muMass},
+		{Name: "Muon_charge", Value: &ana.muCharge},
+		{Name: "MET_pt", Value: &ana.met},
 	}
+}
+
+func (ana *basic5) Book() []hbook.Histogram {
+	ana.hmet = newH1D("hmet", 100, 0, 2000)
+	ana.cuts = newCutFlow(spec5.cuts()...)
+	return []hbook.Histogram{ana.hmet}
+}
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
+func (ana *basic5) Process() error {
+	ana.muons.Load(ana.muPt, ana.muEta, ana.muPhi, ana.muMass, ana.muCharge)
+	muons := ana.muons
 
-	var (
-		hmet = hbook.NewH1D(100, 0, 2000)
-	)
+	ana.cuts.Fill(0, 1)
 
-	sc, err := rtree.NewTreeScannerVars(tree,
-		rtree.ScanVar{Name: "Muon_pt"},
-		rtree.ScanVar{Name: "Muon_eta"},
-		rtree.ScanVar{Name: "Muon_phi"},
-		rtree.ScanVar{Name: "Muon_mass"},
-		rtree.ScanVar{Name: "Muon_charge"},
-		rtree.ScanVar{Name: "MET_sumet"},
-	)
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
+	if len(muons) < spec5.NMuons {
+		return nil
 	}
-	defer sc.Close()
-
-	for sc.Next() {
-		var (
-			muPt     []float32
-			muEta    []float32
-			muPhi    []float32
-			muMass   []float32
-			muCharge []int32
-			met      float32
-		)
-		err := sc.Scan(&muPt, &muEta, &muPhi, &muMass, &muCharge, &met)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
+	ana.cuts.Fill(1, 1)
 
-		nmuons := len(muPt)
-		if nmuons < 2 {
+	var (
+		opposite = false
+		inWindow = false // whether an opposite-sign pair has a mass in the window
+	)
+	for _, c := range muons.Combinations(2) {
+		if muons[c[0]].Charge == muons[c[1]].Charge {
 			continue
 		}
+		opposite = true
 
-		masses := make([]float64, 0, nmuons)
-		combs := combin.Combinations(nmuons, 2)
-		for _, c := range combs {
-			i1 := c[0]
-			i2 := c[1]
-			charge1 := muCharge[i1]
-			charge2 := muCharge[i2]
-			if charge1 == charge2 {
-				continue
-			}
-
-			p1 := fmom.NewPtEtaPhiM(float64(muPt[i1]), float64(muEta[i1]), float64(muPhi[i1]), float64(muMass[i1]))
-			p2 := fmom.NewPtEtaPhiM(float64(muPt[i2]), float64(muEta[i2]), float64(muPhi[i2]), float64(muMass[i2]))
-			mass := fmom.InvMass(&p1, &p2)
-
-			if 60 < mass && mass < 100 {
-				masses = append(masses, mass)
-			}
-		}
-
-		if len(masses) > 0 {
-			hmet.Fill(float64(met), 1)
+		if spec5.inWindow(nanoaod.InvMass(muons, c...)) {
+			inWindow = true
 		}
 	}
 
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
+	if opposite {
+		ana.cuts.Fill(2, 1)
 	}
 
-	fmt.Printf("hmet: %v\n", hmet.SumW())
+	if inWindow {
+		ana.cuts.Fill(3, 1)
+		ana.hmet.Fill(float64(ana.met), 1)
+	}
+	return nil
+}
 
+func (ana *basic5) Plot() Plotter {
 	p := hplot.New()
 	p.X.Label.Text = "MET [GeV]"
 	p.Y.Label.Text = "Nevts"
 
-	p.Add(hplot.NewH1D(hmet))
+	p.Add(hplot.NewH1D(ana.hmet))
 
-	err = p.Save(10*vg.Centimeter, -1, "05-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return p
+}
 
-	return nil
+func (ana *basic5) CutFlow() *CutFlow {
+	return ana.cuts
 }
@@ -0,0 +1,20 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "fmt"
+
+func init() {
+	register("05-rsql", "plots the MET of events with "+spec5.String()+" (rsql)", rsql5)
+}
+
+// rsql5 plots the MEt for events passing the spec5 selection.
+//
+// SQL: the ">= 2 muons" cut (WHERE nMuon >= 2).
+// Go:  the opposite-sign muon pairs and their invariant mass window, as
+// rsqldrv has no combinatorics.
+func rsql5() Analysis {
+	return newRSQL(&basic5{}, fmt.Sprintf("nMuon >= %d", spec5.NMuons))
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+)
+
+func init() {
+	register("05-struct", "plots the MET of events with "+spec5.String()+" (struct)", func() Analysis { return &struct5{} })
+}
+
+// struct5 plots the MEt for events passing the spec5 selection.
+type struct5 struct {
+	evt Event
+
+	hmet *hbook.H1D
+	cuts *CutFlow
+}
+
+func (ana *struct5) Vars() []rtree.ScanVar {
+	return ana.evt.Vars(
+		"Muon_pt", "Muon_eta", "Muon_phi", "Muon_mass", "Muon_charge",
+		"MET_pt",
+	)
+}
+
+func (ana *struct5) Book() []hbook.Histogram {
+	ana.hmet = newH1D("hmet", 100, 0, 2000)
+	ana.cuts = newCutFlow(spec5.cuts()...)
+	return []hbook.Histogram{ana.hmet}
+}
+
+func (ana *struct5) Process() error {
+	evt := &ana.evt
+	evt.Load()
+
+	ana.cuts.Fill(0, 1)
+
+	muons := evt.Muons
+	if len(muons) < spec5.NMuons {
+		return nil
+	}
+	ana.cuts.Fill(1, 1)
+
+	opposite := false
+	for _, c := range muons.Combinations(2) {
+		if muons[c[0]].Charge == muons[c[1]].Charge {
+			continue
+		}
+		opposite = true
+
+		if spec5.inWindow(nanoaod.InvMass(muons, c...)) {
+			ana.cuts.Fill(2, 1)
+			ana.cuts.Fill(3, 1)
+			ana.hmet.Fill(float64(evt.MET.Pt), 1)
+			return nil
+		}
+	}
+
+	if opposite {
+		ana.cuts.Fill(2, 1)
+	}
+	return nil
+}
+
+func (ana *struct5) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "MET [GeV]"
+	p.Y.Label.Text = "Nevts"
+
+	p.Add(hplot.NewH1D(ana.hmet))
+
+	return p
+}
+
+func (ana *struct5) CutFlow() *CutFlow {
+	return ana.cuts
+}
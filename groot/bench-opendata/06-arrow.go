@@ -0,0 +1,73 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("06-arrow", "plots the pT and leading b-tag of the trijet system with mass closest to 172.5 GeV (rarrow)", func() Analysis { return newArrow(&arrow6{}) })
+}
+
+// arrow6 plots the pt of the tri-jet system with mass closest to 172.5 GeV,
+// and the leading b-tag discriminator among the 3 jets in the triplet.
+type arrow6 struct {
+	basic6
+
+	derive bool       // whether to record the quantities derived from each entry
+	tris   []derived6 // quantities derived from each entry of the last frame
+}
+
+func (ana *arrow6) deriveFrames()     { ana.derive = true }
+func (ana *arrow6) deriveEntry(i int) { ana.tri = ana.tris[i] }
+
+func (ana *arrow6) ProcessFrame(f *frame) error {
+	const topMass = 172.5
+
+	var (
+		pt   = f.Jagged("Jet_pt")
+		eta  = f.Jagged("Jet_eta")
+		phi  = f.Jagged("Jet_phi")
+		mass = f.Jagged("Jet_mass")
+		btag = f.Jagged("Jet_btag")
+
+		trijets = combinations(pt.offs, 3)
+		best, _ = trijets.ArgMin(absDiff(trijets.Mass(pt.vals, eta.vals, phi.vals, mass.vals), topMass), nil)
+	)
+
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	fillCut(ana.cuts, 1, atLeast(sizes(pt.offs), 3))
+
+	if ana.derive {
+		ana.tris = resetDerived6(ana.tris, f.Len())
+	}
+
+	for i, n := range best {
+		if n < 0 {
+			continue
+		}
+		p := trijets.Sum(n, pt.vals, eta.vals, phi.vals, mass.vals)
+		ana.h1.Fill(p.Pt(), 1)
+
+		max := 0.0
+		for _, idx := range trijets.idx {
+			if v := float64(btag.vals[idx[n]]); v > max {
+				max = v
+			}
+		}
+		ana.h2.Fill(max, 1)
+
+		if ana.derive {
+			var (
+				beg = pt.offs[i]
+				tri = &ana.tris[i]
+			)
+			for k, idx := range trijets.idx {
+				tri.jets[k] = idx[n] - beg
+			}
+			tri.pt = float32(p.Pt())
+			tri.mass = float32(p.M())
+			tri.btag = float32(max)
+		}
+	}
+	return nil
+}
@@ -5,138 +5,164 @@
 package main
 
 import (
-	"fmt"
 	"math"
 
-	"go-hep.org/x/hep/fmom"
-	"go-hep.org/x/hep/groot"
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/gonum/stat/combin"
-	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 )
 
+func init() {
+	register("06-basic", "plots the pT and leading b-tag of the trijet system with mass closest to 172.5 GeV", func() Analysis { return &basic6{} })
+}
+
 // basic6 plots the pt of the tri-jet system with mass closest to 172.5 GeV,
 // and the leading b-tag discriminator among the 3 jets in the triplet.
-func basic6(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
+type basic6 struct {
+	jetPt   []float32
+	jetEta  []float32
+	jetPhi  []float32
+	jetMass []float32
+	jetBtag []float32
+	jets    nanoaod.Jets
+
+	tri derived6 // quantities derived from the last processed event (see Derived)
+
+	h1   *hbook.H1D
+	h2   *hbook.H1D
+	cuts *CutFlow
+}
 
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
+func (ana *basic6) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "Jet_pt", Value: &ana.jetPt},
+		{Name: "Jet_eta", Value: &ana.jetEta},
+		{Name: "Jet_phi", Value: &ana.jetPhi},
+		{Name: "Jet_mass", Value: &ana.jetMass},
+		{Name: "Jet_btag", Value: &ana.jetBtag},
 	}
+}
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
+func (ana *basic6) Derived() []rtree.WriteVar {
+	return ana.tri.vars()
+}
 
-	var (
-		h1 = hbook.NewH1D(100, 15, 40)
-		h2 = hbook.NewH1D(100, 0, 1)
-	)
+func (ana *basic6) Book() []hbook.Histogram {
+	ana.h1 = newH1D("h1", 100, 0, 300)
+	ana.h2 = newH1D("h2", 100, 0, 1)
+	ana.cuts = newCutFlow("all events", ">= 3 jets")
+	return []hbook.Histogram{ana.h1, ana.h2}
+}
 
-	sc, err := rtree.NewTreeScannerVars(tree,
-		rtree.ScanVar{Name: "Jet_pt"},
-		rtree.ScanVar{Name: "Jet_eta"},
-		rtree.ScanVar{Name: "Jet_phi"},
-		rtree.ScanVar{Name: "Jet_mass"},
-		rtree.ScanVar{Name: "Jet_btag"},
-	)
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
-	}
-	defer sc.Close()
+func (ana *basic6) Process() error {
+	ana.tri.reset()
 
-	for sc.Next() {
-		if sc.Entry() > 1e5 {
-			break
-		}
-		var (
-			jetPt   []float32
-			jetEta  []float32
-			jetPhi  []float32
-			jetMass []float32
-			jetBtag []float32
-		)
-		err := sc.Scan(&jetPt, &jetEta, &jetPhi, &jetMass, &jetBtag)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
+	ana.jets.Load(ana.jetPt, ana.jetEta, ana.jetPhi, ana.jetMass, ana.jetBtag)
+	jets := ana.jets
 
-		njets := len(jetPt)
-		if njets < 3 {
-			continue
-		}
+	ana.cuts.Fill(0, 1)
 
-		idx := findTriJet(jetPt, jetEta, jetPhi, jetMass)
-		btag := 0.0
-		for _, i := range idx {
-			h1.Fill(float64(jetPt[i]), 1)
-			if v := float64(jetBtag[i]); v > btag {
-				btag = v
-			}
-		}
-		h2.Fill(btag, 1)
+	if len(jets) < 3 {
+		return nil
 	}
+	ana.cuts.Fill(1, 1)
 
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
+	idx := findTriJet(jets)
+	p := nanoaod.Sum(jets, idx[:]...)
+	ana.h1.Fill(p.Pt(), 1)
+
+	btag := 0.0
+	for _, i := range idx {
+		if v := float64(jets[i].Btag); v > btag {
+			btag = v
+		}
 	}
+	ana.h2.Fill(btag, 1)
 
-	fmt.Printf("h1: %v\n", h1.SumW())
-	fmt.Printf("h2: %v\n", h2.SumW())
+	ana.tri = derived6{
+		jets: [3]int32{int32(idx[0]), int32(idx[1]), int32(idx[2])},
+		pt:   float32(p.Pt()),
+		mass: float32(p.M()),
+		btag: float32(btag),
+	}
+	return nil
+}
 
+func (ana *basic6) Plot() Plotter {
 	tp := hplot.NewTiledPlot(draw.Tiles{Cols: 1, Rows: 2})
 
 	p1 := tp.Plots[0]
 	p1.X.Label.Text = "Trijet Pt [GeV]"
 	p1.Y.Label.Text = "Nevts"
-	p1.Add(hplot.NewH1D(h1))
+	p1.Add(hplot.NewH1D(ana.h1))
 
 	p2 := tp.Plots[1]
 	p2.X.Label.Text = "Trijet leading b-tag"
 	p2.Y.Label.Text = "Nevts"
-	p2.Add(hplot.NewH1D(h2))
+	p2.Add(hplot.NewH1D(ana.h2))
 
-	err = tp.Save(10*vg.Centimeter, -1, "06-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return tp
+}
 
-	return nil
+func (ana *basic6) CutFlow() *CutFlow {
+	return ana.cuts
 }
 
-func findTriJet(pt, eta, phi, mass []float32) [3]int {
-	makePtEtaPhiM := func(i int) *fmom.PtEtaPhiM {
-		p := fmom.NewPtEtaPhiM(float64(pt[i]), float64(eta[i]), float64(phi[i]), float64(mass[i]))
-		return &p
-	}
+// findTriJet returns the indices of the 3 jets whose system has the mass
+// closest to the top quark mass.
+func findTriJet(jets nanoaod.Jets) [3]int {
+	const topMass = 172.5 // could use go-hep.org/x/hep/heppdt.Particle.Mass, though.
 
 	var (
-		combs = combin.Combinations(len(pt), 3)
+		tri   = [3]int{0, 1, 2}
 		delta = math.MaxFloat64
 	)
-
-	const topMass = 172.5 // could use go-hep.org/x/hep/heppdt.Particle.Mass, though.
-
-	idx := 0
-	for i, c := range combs {
-		p1 := makePtEtaPhiM(c[0])
-		p2 := makePtEtaPhiM(c[1])
-		p3 := makePtEtaPhiM(c[2])
-
-		m := fmom.Add(fmom.Add(p1, p2), p3).M()
+	for _, c := range jets.Combinations(3) {
+		m := nanoaod.InvMass(jets, c...)
 		if d := math.Abs(m - topMass); d < delta {
 			delta = d
-			idx = i
+			tri = [3]int{c[0], c[1], c[2]}
 		}
 	}
 
-	tri := combs[idx]
-	return [3]int{tri[0], tri[1], tri[2]}
+	return tri
+}
+
+// derived6 holds the quantities derived by the 06 analyses from an event,
+// written to their friend trees.
+type derived6 struct {
+	jets [3]int32 // indices of the jets of the best trijet (-1 if < 3 jets)
+	pt   float32  // pT of the best trijet
+	mass float32  // invariant mass of the best trijet
+	btag float32  // leading b-tag discriminator among the jets of the best trijet
+}
+
+// reset sets the derived quantities to their values for events without
+// trijet.
+func (d *derived6) reset() {
+	*d = derived6{jets: [3]int32{-1, -1, -1}}
+}
+
+// resetDerived6 returns a slice of n reset derived quantities, reusing the
+// storage of ds if possible.
+func resetDerived6(ds []derived6, n int) []derived6 {
+	if cap(ds) < n {
+		ds = make([]derived6, n)
+	}
+	ds = ds[:n]
+	for i := range ds {
+		ds[i].reset()
+	}
+	return ds
+}
+
+func (d *derived6) vars() []rtree.WriteVar {
+	return []rtree.WriteVar{
+		{Name: "Trijet_jets", Value: &d.jets},
+		{Name: "Trijet_pt", Value: &d.pt},
+		{Name: "Trijet_mass", Value: &d.mass},
+		{Name: "Trijet_btag", Value: &d.btag},
+	}
 }
@@ -0,0 +1,19 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("06-rsql", "plots the pT and leading b-tag of the trijet system with mass closest to 172.5 GeV (rsql)", rsql6)
+}
+
+// rsql6 plots the pt of the tri-jet system with mass closest to 172.5 GeV,
+// and the leading b-tag discriminator among the 3 jets in the triplet.
+//
+// SQL: the ">= 3 jets" cut (WHERE nJet >= 3).
+// Go:  the trijet combinatorics, their invariant mass and the leading
+// b-tag of the chosen triplet.
+func rsql6() Analysis {
+	return newRSQL(&basic6{}, "nJet >= 3")
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+func init() {
+	register("06-struct", "plots the pT and leading b-tag of the trijet system with mass closest to 172.5 GeV (struct)", func() Analysis { return &struct6{} })
+}
+
+// struct6 plots the pt of the tri-jet system with mass closest to 172.5 GeV,
+// and the leading b-tag discriminator among the 3 jets in the triplet.
+type struct6 struct {
+	evt Event
+	tri derived6 // quantities derived from the last processed event (see Derived)
+
+	h1   *hbook.H1D
+	h2   *hbook.H1D
+	cuts *CutFlow
+}
+
+func (ana *struct6) Vars() []rtree.ScanVar {
+	return ana.evt.Vars("Jet_pt", "Jet_eta", "Jet_phi", "Jet_mass", "Jet_btag")
+}
+
+func (ana *struct6) Derived() []rtree.WriteVar {
+	return ana.tri.vars()
+}
+
+func (ana *struct6) Book() []hbook.Histogram {
+	ana.h1 = newH1D("h1", 100, 0, 300)
+	ana.h2 = newH1D("h2", 100, 0, 1)
+	ana.cuts = newCutFlow("all events", ">= 3 jets")
+	return []hbook.Histogram{ana.h1, ana.h2}
+}
+
+func (ana *struct6) Process() error {
+	evt := &ana.evt
+	evt.Load()
+	ana.tri.reset()
+
+	ana.cuts.Fill(0, 1)
+
+	if len(evt.Jets) < 3 {
+		return nil
+	}
+	ana.cuts.Fill(1, 1)
+
+	idx := findTriJet(evt.Jets)
+	p := nanoaod.Sum(evt.Jets, idx[:]...)
+	ana.h1.Fill(p.Pt(), 1)
+
+	btag := 0.0
+	for _, i := range idx {
+		if v := float64(evt.Jets[i].Btag); v > btag {
+			btag = v
+		}
+	}
+	ana.h2.Fill(btag, 1)
+
+	ana.tri = derived6{
+		jets: [3]int32{int32(idx[0]), int32(idx[1]), int32(idx[2])},
+		pt:   float32(p.Pt()),
+		mass: float32(p.M()),
+		btag: float32(btag),
+	}
+	return nil
+}
+
+func (ana *struct6) Plot() Plotter {
+	tp := hplot.NewTiledPlot(draw.Tiles{Cols: 1, Rows: 2})
+
+	p1 := tp.Plots[0]
+	p1.X.Label.Text = "Trijet Pt [GeV]"
+	p1.Y.Label.Text = "Nevts"
+	p1.Add(hplot.NewH1D(ana.h1))
+
+	p2 := tp.Plots[1]
+	p2.X.Label.Text = "Trijet leading b-tag"
+	p2.Y.Label.Text = "Nevts"
+	p2.Add(hplot.NewH1D(ana.h2))
+
+	return tp
+}
+
+func (ana *struct6) CutFlow() *CutFlow {
+	return ana.cuts
+}
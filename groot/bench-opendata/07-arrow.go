@@ -0,0 +1,46 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "math"
+
+func init() {
+	register("07-arrow", "plots the scalar sum of the pT of jets isolated from leptons (rarrow)", func() Analysis { return newArrow(&arrow7{}) })
+}
+
+// arrow7 plots the sum of the pt of all jets of pt > 30 GeV
+// that are not within DR 0.4 from a lepton of pt > 10 GeV.
+type arrow7 struct {
+	basic7
+}
+
+func (ana *arrow7) ProcessFrame(f *frame) error {
+	const dr2Min = 0.4 * 0.4
+
+	var (
+		jetPt  = f.Jagged("Jet_pt")
+		jetEta = f.Jagged("Jet_eta")
+		jetPhi = f.Jagged("Jet_phi")
+		muPt   = f.Jagged("Muon_pt")
+		elePt  = f.Jagged("Electron_pt")
+
+		drMu  = minDeltaR2(jetEta, jetPhi, f.Jagged("Muon_eta"), f.Jagged("Muon_phi"), gt(muPt.vals, 10))
+		drEle = minDeltaR2(jetEta, jetPhi, f.Jagged("Electron_eta"), f.Jagged("Electron_phi"), gt(elePt.vals, 10))
+		jets  = gt(jetPt.vals, 30)
+	)
+
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	fillCut(ana.cuts, 1, atLeast(sizes(jetPt.offs), 1))
+	fillCut(ana.cuts, 2, atLeast(count(jetPt.offs, jets), 1))
+
+	for i, ok := range jets {
+		jets[i] = ok && math.Min(drEle[i], drMu[i]) > dr2Min
+	}
+
+	evts := atLeast(count(jetPt.offs, jets), 1)
+	fillCut(ana.cuts, 3, evts)
+	fillF64(ana.h1, sum(jetPt.offs, jetPt.vals, jets), evts)
+	return nil
+}
@@ -5,152 +5,118 @@
 package main
 
 import (
-	"fmt"
-	"math"
-
-	"go-hep.org/x/hep/groot"
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/plot/vg"
 )
 
+func init() {
+	register("07-basic", "plots the scalar sum of the pT of jets isolated from leptons", func() Analysis { return &basic7{} })
+}
+
 // basic7 plots the sum of the pt of all jets of pt > 30 GeV
 // that are not within DR 0.4 from a lepton of pt > 10 GeV.
-func basic7(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
+type basic7 struct {
+	jetPt  []float32
+	jetEta []float32
+	jetPhi []float32
+	muPt   []float32
+	muEta  []float32
+	muPhi  []float32
+	elePt  []float32
+	eleEta []float32
+	elePhi []float32
+
+	jets      nanoaod.Jets
+	muons     nanoaod.Muons
+	electrons nanoaod.Electrons
+
+	h1   *hbook.H1D
+	cuts *CutFlow
+}
 
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
+func (ana *basic7) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "Jet_pt", Value: &ana.jetPt},
+		{Name: "Jet_eta", Value: &ana.jetEta},
+		{Name: "Jet_phi", Value: &ana.jetPhi},
+		{Name: "Muon_pt", Value: &ana.muPt},
+		{Name: "Muon_eta", Value: &ana.muEta},
+		{Name: "Muon_phi", Value: &ana.muPhi},
+		{Name: "Electron_pt", Value: &ana.elePt},
+		{Name: "Electron_eta", Value: &ana.eleEta},
+		{Name: "Electron_phi", Value: &ana.elePhi},
 	}
+}
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
-
-	var (
-		h1 = hbook.NewH1D(100, 15, 200)
-	)
-
-	sc, err := rtree.NewTreeScannerVars(tree,
-		rtree.ScanVar{Name: "Jet_pt"},
-		rtree.ScanVar{Name: "Jet_eta"},
-		rtree.ScanVar{Name: "Jet_phi"},
-		rtree.ScanVar{Name: "Muon_pt"},
-		rtree.ScanVar{Name: "Muon_eta"},
-		rtree.ScanVar{Name: "Muon_phi"},
-		rtree.ScanVar{Name: "Electron_pt"},
-		rtree.ScanVar{Name: "Electron_eta"},
-		rtree.ScanVar{Name: "Electron_phi"},
+func (ana *basic7) Book() []hbook.Histogram {
+	ana.h1 = newH1D("h1", 100, 15, 200)
+	ana.cuts = newCutFlow(
+		"all events",
+		">= 1 jet",
+		">= 1 jet with pT > 30 GeV",
+		">= 1 isolated jet with pT > 30 GeV",
 	)
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
-	}
-	defer sc.Close()
-
-	for sc.Next() {
-		if sc.Entry() > 1e5 {
-			break
-		}
-		var (
-			jetPt  []float32
-			jetEta []float32
-			jetPhi []float32
-			muPt   []float32
-			muEta  []float32
-			muPhi  []float32
-			elePt  []float32
-			eleEta []float32
-			elePhi []float32
-		)
-		err := sc.Scan(&jetPt, &jetEta, &jetPhi, &muPt, &muEta, &muPhi, &elePt, &eleEta, &elePhi)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
+	return []hbook.Histogram{ana.h1}
+}
 
-		njets := len(jetPt)
-		if njets < 1 {
-			continue
-		}
+func (ana *basic7) Process() error {
+	ana.jets.Load(ana.jetPt, ana.jetEta, ana.jetPhi, nil, nil)
+	ana.muons.Load(ana.muPt, ana.muEta, ana.muPhi, nil, nil)
+	ana.electrons.Load(ana.elePt, ana.eleEta, ana.elePhi, nil, nil)
 
-		var (
-			jets = goodJets(
-				jetPt, jetEta, jetPhi,
-				elePt, eleEta, elePhi,
-				muPt, muEta, muPhi,
-			)
-		)
+	ana.cuts.Fill(0, 1)
 
-		if len(jets) == 0 {
-			continue
-		}
+	if len(ana.jets) < 1 {
+		return nil
+	}
+	ana.cuts.Fill(1, 1)
 
-		pt := 0.0
-		for _, i := range jets {
-			pt += float64(jetPt[i])
+	for _, jet := range ana.jets {
+		if jet.Pt > 30 {
+			ana.cuts.Fill(2, 1)
+			break
 		}
-		h1.Fill(pt, 1)
 	}
 
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
+	isolatedJets(&ana.jets, &ana.muons, &ana.electrons)
+	if len(ana.jets) == 0 {
+		return nil
 	}
+	ana.cuts.Fill(3, 1)
 
-	fmt.Printf("h1: %v\n", h1.SumW())
+	pt := 0.0
+	for _, jet := range ana.jets {
+		pt += float64(jet.Pt)
+	}
+	ana.h1.Fill(pt, 1)
+	return nil
+}
 
+func (ana *basic7) Plot() Plotter {
 	p := hplot.New()
 	p.X.Label.Text = "Jet Pt sum [GeV]"
 	p.Y.Label.Text = "Nevts"
-	p.Add(hplot.NewH1D(h1))
+	p.Add(hplot.NewH1D(ana.h1))
 
-	err = p.Save(10*vg.Centimeter, -1, "07-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
-
-	return nil
+	return p
 }
 
-func goodJets(pt1, eta1, phi1, pt2, eta2, phi2, pt3, eta3, phi3 []float32) []int {
-	const (
-		dr2Min = 0.4 * 0.4
-	)
-
-	jets := make([]int, 0, len(pt1))
-	for ijet, jetPt := range pt1 {
-		if jetPt <= 30 {
-			continue
-		}
-		dr2 := math.Min(
-			jetIsol(ijet, pt1, eta1, phi1, pt2, eta2, phi2),
-			jetIsol(ijet, pt1, eta1, phi1, pt3, eta3, phi3),
-		)
-		if dr2 > dr2Min {
-			// jet is isolated.
-			jets = append(jets, ijet)
-		}
-	}
-
-	return jets
+func (ana *basic7) CutFlow() *CutFlow {
+	return ana.cuts
 }
 
-func jetIsol(ijet int, pt1, eta1, phi1, pt2, eta2, phi2 []float32) float64 {
-	const (
-		twopi = 2 * math.Pi
-	)
-
-	dr2 := math.Inf(+1)
-	for ilep, lepPt := range pt2 {
-		if lepPt <= 10 {
-			continue
-		}
-		dphi := -math.Remainder(float64(phi1[ijet]-phi2[ilep]), twopi)
-		deta := float64(eta1[ijet] - eta2[ilep])
-		dr2 = math.Min(dphi*dphi+deta*deta, dr2)
-	}
-	return dr2
+// isolatedJets keeps, in place, the jets with pT > 30 GeV farther than 0.4
+// in (eta, phi) from all the leptons with pT > 10 GeV.
+// The muons and electrons are also filtered in place, to keep only the
+// leptons with pT > 10 GeV.
+func isolatedJets(jets *nanoaod.Jets, muons *nanoaod.Muons, electrons *nanoaod.Electrons) {
+	hard := func(lep *nanoaod.Lepton) bool { return lep.Pt > 10 }
+	muons.Keep(hard)
+	electrons.Keep(hard)
+	jets.Keep(func(jet *nanoaod.Jet) bool {
+		return jet.Pt > 30
+	})
+	jets.KeepIsolated(0.4, electrons, muons)
 }
@@ -0,0 +1,20 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("07-rsql", "plots the scalar sum of the pT of jets isolated from leptons (rsql)", rsql7)
+}
+
+// rsql7 plots the sum of the pt of all jets of pt > 30 GeV
+// that are not within DR 0.4 from a lepton of pt > 10 GeV.
+//
+// SQL: the ">= 1 jet" cut (WHERE nJet >= 1).
+// Go:  the pT cuts on each jet and lepton, the DR isolation of the jets
+// and the scalar sum of their pT, as rsqldrv can neither apply cuts to the
+// elements of jagged columns nor sum them.
+func rsql7() Analysis {
+	return newRSQL(&basic7{}, "nJet >= 1")
+}
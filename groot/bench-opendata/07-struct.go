@@ -0,0 +1,87 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+)
+
+func init() {
+	register("07-struct", "plots the scalar sum of the pT of jets isolated from leptons (struct)", func() Analysis { return &struct7{} })
+}
+
+// struct7 plots the sum of the pt of all jets of pt > 30 GeV
+// that are not within DR 0.4 from a lepton of pt > 10 GeV.
+type struct7 struct {
+	evt Event
+
+	h1   *hbook.H1D
+	cuts *CutFlow
+}
+
+func (ana *struct7) Vars() []rtree.ScanVar {
+	return ana.evt.Vars(
+		"Jet_pt", "Jet_eta", "Jet_phi",
+		"Muon_pt", "Muon_eta", "Muon_phi",
+		"Electron_pt", "Electron_eta", "Electron_phi",
+	)
+}
+
+func (ana *struct7) Book() []hbook.Histogram {
+	ana.h1 = newH1D("h1", 100, 15, 200)
+	ana.cuts = newCutFlow(
+		"all events",
+		">= 1 jet",
+		">= 1 jet with pT > 30 GeV",
+		">= 1 isolated jet with pT > 30 GeV",
+	)
+	return []hbook.Histogram{ana.h1}
+}
+
+func (ana *struct7) Process() error {
+	evt := &ana.evt
+	evt.Load()
+
+	ana.cuts.Fill(0, 1)
+	if len(evt.Jets) > 0 {
+		ana.cuts.Fill(1, 1)
+	}
+
+	for _, jet := range evt.Jets {
+		if jet.Pt > 30 {
+			ana.cuts.Fill(2, 1)
+			break
+		}
+	}
+
+	isolatedJets(&evt.Jets, &evt.Muons, &evt.Electrons)
+	if len(evt.Jets) == 0 {
+		return nil
+	}
+	ana.cuts.Fill(3, 1)
+
+	pt := 0.0
+	for _, jet := range evt.Jets {
+		pt += float64(jet.Pt)
+	}
+
+	ana.h1.Fill(pt, 1)
+	return nil
+}
+
+func (ana *struct7) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "Jet Pt sum [GeV]"
+	p.Y.Label.Text = "Nevts"
+	p.Add(hplot.NewH1D(ana.h1))
+
+	return p
+}
+
+func (ana *struct7) CutFlow() *CutFlow {
+	return ana.cuts
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "go-hep.org/x/hep/fmom"
+
+func init() {
+	register("08-arrow", "plots the transverse mass of the MET and the leading other lepton in events with a Z candidate and >=3 leptons (rarrow)", func() Analysis { return newArrow(&arrow8{}) })
+}
+
+// arrow8 runs the following analysis:
+// in events with >=3 leptons and a same-flavour opposite-sign lepton pair,
+// find the best same-flavour opposite-sign lepton pair (mass closest to 91.2 GeV),
+// and plot the transverse mass of the missing energy and the leading other lepton
+type arrow8 struct {
+	basic8
+
+	derive bool       // whether to record the quantities derived from each entry
+	zs     []derived8 // quantities derived from each entry of the last frame
+}
+
+func (ana *arrow8) deriveFrames()     { ana.derive = true }
+func (ana *arrow8) deriveEntry(i int) { ana.z = ana.zs[i] }
+
+func (ana *arrow8) ProcessFrame(f *frame) error {
+	var (
+		muPt  = f.Jagged("Muon_pt")
+		elePt = f.Jagged("Electron_pt")
+
+		muPairs, imu, dmu    = zCandidates(f, "Muon")
+		elePairs, iele, dele = zCandidates(f, "Electron")
+
+		nmu   = sizes(muPt.offs)
+		nele  = sizes(elePt.offs)
+		evts  = make(mask, f.Len())
+		mus   = all(len(muPt.vals))
+		eles  = all(len(elePt.vals))
+		muP4  = p4s(f, "Muon")
+		eleP4 = p4s(f, "Electron")
+	)
+
+	if ana.derive {
+		ana.zs = resetDerived8(ana.zs, f.Len())
+	}
+
+	ana.cuts.fillN(0, int64(f.Len()), 1)
+	for i := range evts {
+		if nmu[i]+nele[i] < 3 {
+			continue
+		}
+		ana.cuts.Fill(1, 1)
+
+		if imu[i] < 0 && iele[i] < 0 {
+			continue
+		}
+		ana.cuts.Fill(2, 1)
+		evts[i] = true
+
+		// exclude the leptons of the Z candidate.
+		switch {
+		case dmu[i] < dele[i]:
+			mus[muPairs.idx[0][imu[i]]] = false
+			mus[muPairs.idx[1][imu[i]]] = false
+		default:
+			eles[elePairs.idx[0][iele[i]]] = false
+			eles[elePairs.idx[1][iele[i]]] = false
+		}
+
+		if ana.derive {
+			switch {
+			case dmu[i] < dele[i]:
+				ana.zs[i] = zDerived(13, muPairs, imu[i], muPt.offs[i], muP4)
+			default:
+				ana.zs[i] = zDerived(11, elePairs, iele[i], elePt.offs[i], eleP4)
+			}
+		}
+	}
+
+	var (
+		muLead  = argLeading(muPt.offs, muPt.vals, mus)
+		eleLead = argLeading(elePt.offs, elePt.vals, eles)
+		metPt   = f.Float32s("MET_pt")
+		metPhi  = f.Float32s("MET_phi")
+		mt      = make([]float64, f.Len())
+	)
+	for i := range mt {
+		if !evts[i] {
+			continue
+		}
+		var lep fmom.PtEtaPhiM
+		switch imu, iele := muLead[i], eleLead[i]; {
+		case iele >= 0 && (imu < 0 || elePt.vals[iele] > muPt.vals[imu]):
+			lep = eleP4(iele)
+		case imu >= 0:
+			lep = muP4(imu)
+		default:
+			evts[i] = false
+			if ana.derive {
+				ana.zs[i].reset()
+			}
+			continue
+		}
+		mt[i] = transverseMass(lep, metPt[i], metPhi[i])
+
+		if ana.derive {
+			ana.zs[i].lepPt = float32(lep.Pt())
+			ana.zs[i].mt = float32(mt[i])
+		}
+	}
+
+	fillF64(ana.hmt, mt, evts)
+	return nil
+}
+
+// zCandidates returns the pairs of leptons of the provided flavour and,
+// for each event, the index of the opposite-sign pair with mass closest
+// to 91.2 GeV (-1 if none) and the distance of its mass to the Z mass.
+func zCandidates(f *frame, flavour string) (combs, []int, []float64) {
+	const zMass = 91.2
+
+	var (
+		pt     = f.Jagged(flavour + "_pt")
+		eta    = f.Jagged(flavour + "_eta")
+		phi    = f.Jagged(flavour + "_phi")
+		mass   = f.Jagged(flavour + "_mass")
+		charge = f.JaggedI32(flavour + "_charge")
+
+		pairs = combinations(pt.offs, 2)
+		mll   = pairs.Mass(pt.vals, eta.vals, phi.vals, mass.vals)
+	)
+
+	best, d := pairs.ArgMin(absDiff(mll, zMass), pairs.OppositeCharge(charge.vals))
+	return pairs, best, d
+}
+
+// zDerived returns the quantities derived from a Z candidate made of the
+// n-th pair of leptons of the provided flavour, whose entry starts at the
+// flat index beg, with the other lepton yet to be set.
+func zDerived(flavour int32, pairs combs, n int, beg int32, p4 func(i int32) fmom.PtEtaPhiM) derived8 {
+	var (
+		i1 = pairs.idx[0][n]
+		i2 = pairs.idx[1][n]
+		p1 = p4(i1)
+		p2 = p4(i2)
+	)
+	return derived8{
+		flavour: flavour,
+		leps:    [2]int32{i1 - beg, i2 - beg},
+		mass:    float32(fmom.InvMass(&p1, &p2)),
+	}
+}
+
+// p4s returns a function returning the 4-momentum of the i-th element of
+// the flat columns of the leptons of the provided flavour.
+func p4s(f *frame, flavour string) func(i int32) fmom.PtEtaPhiM {
+	var (
+		pt   = f.Jagged(flavour + "_pt").vals
+		eta  = f.Jagged(flavour + "_eta").vals
+		phi  = f.Jagged(flavour + "_phi").vals
+		mass = f.Jagged(flavour + "_mass").vals
+	)
+	return func(i int32) fmom.PtEtaPhiM {
+		return fmom.NewPtEtaPhiM(float64(pt[i]), float64(eta[i]), float64(phi[i]), float64(mass[i]))
+	}
+}
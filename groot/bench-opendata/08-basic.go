@@ -5,173 +5,140 @@
 package main
 
 import (
-	"fmt"
 	"math"
 
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
 	"go-hep.org/x/hep/fmom"
-	"go-hep.org/x/hep/groot"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
-	"gonum.org/v1/gonum/stat/combin"
-	"gonum.org/v1/plot/vg"
-	"gonum.org/v1/plot/vg/draw"
 )
 
+func init() {
+	register("08-basic", "plots the transverse mass of the MET and the leading other lepton in events with a Z candidate and >=3 leptons", func() Analysis { return &basic8{} })
+}
+
 // basic8 runs the following analysis:
 // in events with >=3 leptons and a same-flavour opposite-sign lepton pair,
 // find the best same-flavour opposite-sign lepton pair (mass closest to 91.2 GeV),
 // and plot the transverse mass of the missing energy and the leading other lepton
-func basic8(fname string) error {
-	f, err := groot.Open(fname)
-	if err != nil {
-		return fmt.Errorf("could not open ROOT file: %w", err)
-	}
-	defer f.Close()
+type basic8 struct {
+	muPt      []float32
+	muEta     []float32
+	muPhi     []float32
+	muMass    []float32
+	muCharge  []int32
+	elePt     []float32
+	eleEta    []float32
+	elePhi    []float32
+	eleMass   []float32
+	eleCharge []int32
+	metPt     float32
+	metPhi    float32
 
-	o, err := f.Get("Events")
-	if err != nil {
-		return fmt.Errorf("could not retrieve tree: %w", err)
-	}
+	muons     nanoaod.Muons
+	electrons nanoaod.Electrons
 
-	tree := o.(rtree.Tree)
-	fmt.Printf("tree: %d entries\n", tree.Entries())
+	z derived8 // quantities derived from the last processed event (see Derived)
 
-	var (
-		hmet = hbook.NewH1D(100, 0, 2000)
-		hlep = hbook.NewH1D(100, 15, 60)
-	)
+	hmt  *hbook.H1D
+	cuts *CutFlow
+}
 
-	sc, err := rtree.NewTreeScannerVars(tree,
-		rtree.ScanVar{Name: "Muon_pt"},
-		rtree.ScanVar{Name: "Muon_eta"},
-		rtree.ScanVar{Name: "Muon_phi"},
-		rtree.ScanVar{Name: "Muon_mass"},
-		rtree.ScanVar{Name: "Muon_charge"},
-		rtree.ScanVar{Name: "Electron_pt"},
-		rtree.ScanVar{Name: "Electron_eta"},
-		rtree.ScanVar{Name: "Electron_phi"},
-		rtree.ScanVar{Name: "Electron_mass"},
-		rtree.ScanVar{Name: "Electron_charge"},
-		rtree.ScanVar{Name: "MET_sumet"},
-	)
-	if err != nil {
-		return fmt.Errorf("could not create scanner: %w", err)
+func (ana *basic8) Vars() []rtree.ScanVar {
+	return []rtree.ScanVar{
+		{Name: "Muon_pt", Value: &ana.muPt},
+		{Name: "Muon_eta", Value: &ana.muEta},
+		{Name: "Muon_phi", Value: &ana.muPhi},
+		{Name: "Muon_mass", Value: &ana.muMass},
+		{Name: "Muon_charge", Value: &ana.muCharge},
+		{Name: "Electron_pt", Value: &ana.elePt},
+		{Name: "Electron_eta", Value: &ana.eleEta},
+		{Name: "Electron_phi", Value: &ana.elePhi},
+		{Name: "Electron_mass", Value: &ana.eleMass},
+		{Name: "Electron_charge", Value: &ana.eleCharge},
+		{Name: "MET_pt", Value: &ana.metPt},
+		{Name: "MET_phi", Value: &ana.metPhi},
 	}
-	defer sc.Close()
+}
 
-	for sc.Next() {
-		if sc.Entry() > 1e5 {
-			break
-		}
-		var (
-			muPt      []float32
-			muEta     []float32
-			muPhi     []float32
-			muMass    []float32
-			muCharge  []int32
-			elePt     []float32
-			eleEta    []float32
-			elePhi    []float32
-			eleMass   []float32
-			eleCharge []int32
-			sumet     float32
-		)
-		err := sc.Scan(
-			&muPt, &muEta, &muPhi, &muMass, &muCharge,
-			&elePt, &eleEta, &elePhi, &eleMass, &eleCharge,
-			&sumet,
-		)
-		if err != nil {
-			return fmt.Errorf("error during scan: %w", err)
-		}
+func (ana *basic8) Derived() []rtree.WriteVar {
+	return ana.z.vars()
+}
 
-		nleptons := len(muPt) + len(elePt)
-		if nleptons < 3 {
-			continue
-		}
+func (ana *basic8) Book() []hbook.Histogram {
+	ana.hmt = newH1D("hmt", 100, 0, 200)
+	ana.cuts = newCutFlow(
+		"all events",
+		">= 3 leptons",
+		"same-flavour opposite-sign lepton pair",
+	)
+	return []hbook.Histogram{ana.hmt}
+}
 
-		var (
-			imu1,  imu2,  dmu  = findLeptonPair(muPt,  muEta,  muPhi,  muMass,  muCharge)
-			iele1, iele2, dele = findLeptonPair(elePt, eleEta, elePhi, eleMass, eleCharge)
-		)
+func (ana *basic8) Process() error {
+	ana.muons.Load(ana.muPt, ana.muEta, ana.muPhi, ana.muMass, ana.muCharge)
+	ana.electrons.Load(ana.elePt, ana.eleEta, ana.elePhi, ana.eleMass, ana.eleCharge)
 
-		if imu1 < 0 && iele1 < 0 {
-			continue
-		}
+	ana.z.reset()
 
-		if dmu < dele {
-			iele1 = -1
-			iele2 = -1
-		} else {
-			imu1 = -1
-			imu2 = -1
-		}
+	ana.cuts.Fill(0, 1)
 
-		var lepPt float32
-		if imu1 >= 0 {
-			for i, pt := range muPt {
-				if i == imu1 || i == imu2 {
-					continue
-				}
-				if pt > lepPt {
-					lepPt = pt
-				}
-			}
-			for _, pt := range elePt {
-				if pt > lepPt {
-					lepPt = pt
-				}
-			}
-		}
-		if iele1 >= 0 {
-			for i, pt := range elePt {
-				if i == iele1 || i == iele2 {
-					continue
-				}
-				if pt > lepPt {
-					lepPt = pt
-				}
-			}
-			for _, pt := range muPt {
-				if pt > lepPt {
-					lepPt = pt
-				}
-			}
-		}
+	if len(ana.muons)+len(ana.electrons) < 3 {
+		return nil
+	}
+	ana.cuts.Fill(1, 1)
+
+	var (
+		imu1, imu2, dmu    = findLeptonPair(ana.muons)
+		iele1, iele2, dele = findLeptonPair(ana.electrons)
+	)
 
-		hmet.Fill(float64(sumet), 1)
-		hlep.Fill(float64(lepPt), 1)
+	if imu1 < 0 && iele1 < 0 {
+		return nil
 	}
+	ana.cuts.Fill(2, 1)
 
-	if err := sc.Err(); err != nil {
-		return fmt.Errorf("could not scan whole file: %w", err)
+	var (
+		muZ    = dmu < dele
+		i1, i2 = iele1, iele2
+		leps   = nanoaod.Leptons(ana.electrons)
+	)
+	if muZ {
+		i1, i2 = imu1, imu2
+		leps = ana.muons
+	}
+	lep, ok := otherLepton(ana.muons, ana.electrons, muZ, i1, i2)
+	if !ok {
+		return nil
 	}
+	mt := transverseMass(lep, ana.metPt, ana.metPhi)
 
-	fmt.Printf("hmet: %v\n", hmet.SumW())
-	fmt.Printf("hlep: %v\n", hlep.SumW())
+	ana.hmt.Fill(mt, 1)
 
-	tp := hplot.NewTiledPlot(draw.Tiles{Cols: 1, Rows: 2})
+	ana.z = newDerived8(muZ, leps, i1, i2, lep, mt)
+	return nil
+}
 
-	p1 := tp.Plots[0]
-	p1.X.Label.Text = "MET [GeV]"
-	p1.Y.Label.Text = "Nevts"
-	p1.Add(hplot.NewH1D(hmet))
+func (ana *basic8) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "Transverse mass [GeV]"
+	p.Y.Label.Text = "Nevts"
 
-	p2 := tp.Plots[1]
-	p2.X.Label.Text = "Lepton Pt [GeV]"
-	p2.Y.Label.Text = "Nevts"
-	p2.Add(hplot.NewH1D(hlep))
+	p.Add(hplot.NewH1D(ana.hmt))
 
-	err = tp.Save(10*vg.Centimeter, -1, "08-basic.png")
-	if err != nil {
-		return fmt.Errorf("could not save plot: %w", err)
-	}
+	return p
+}
 
-	return nil
+func (ana *basic8) CutFlow() *CutFlow {
+	return ana.cuts
 }
 
-func findLeptonPair(pt, eta, phi, mass []float32, charge []int32) (int, int, float64) {
+// findLeptonPair returns the indices of the opposite-sign pair of leptons
+// whose invariant mass is the closest to the Z mass, and the distance to the
+// Z mass.
+// findLeptonPair returns -1 indices if no such pair exists.
+func findLeptonPair(leps nanoaod.Leptons) (int, int, float64) {
 	const (
 		zMass = 91.2 // or take it from go-hep.org/x/hep/heppdt.PDT[id].Particle.Mass
 	)
@@ -185,25 +152,13 @@ func findLeptonPair(pt, eta, phi, mass []float32, charge []int32) (int, int, flo
 		i2: -1,
 	}
 
-	if len(pt) < 2 {
-		return cand.i1, cand.i2, cand.d
-	}
-
-	makePtEtaPhiM := func(i int) fmom.PtEtaPhiM {
-		return fmom.NewPtEtaPhiM(float64(pt[i]), float64(eta[i]), float64(phi[i]), float64(mass[i]))
-	}
-
-	combs := combin.Combinations(len(pt), 2)
-	for _, c := range combs {
+	for _, c := range leps.Combinations(2) {
 		i1 := c[0]
 		i2 := c[1]
-		if charge[i1] == charge[i2] {
+		if leps.Charge(i1) == leps.Charge(i2) {
 			continue
 		}
-		p1 := makePtEtaPhiM(i1)
-		p2 := makePtEtaPhiM(i2)
-		mll := fmom.InvMass(&p1, &p2)
-		d := math.Abs(mll-zMass)
+		d := math.Abs(nanoaod.InvMass(leps, i1, i2) - zMass)
 		if d < cand.d {
 			cand.d = d
 			cand.i1 = i1
@@ -213,3 +168,95 @@ func findLeptonPair(pt, eta, phi, mass []float32, charge []int32) (int, int, flo
 
 	return cand.i1, cand.i2, cand.d
 }
+
+// otherLepton returns the 4-momentum of the leading lepton outside of the Z
+// candidate made of the leptons i1 and i2 of the muons (if muZ) or of the
+// electrons, and whether there is such a lepton.
+func otherLepton(muons nanoaod.Muons, electrons nanoaod.Electrons, muZ bool, i1, i2 int) (fmom.PtEtaPhiM, bool) {
+	var (
+		leps nanoaod.Leptons
+		lead = -1
+		pt   float32
+		skip = func(muon bool, i int) bool {
+			return muon == muZ && (i == i1 || i == i2)
+		}
+	)
+	for i, mu := range muons {
+		if !skip(true, i) && mu.Pt > pt {
+			leps, lead, pt = muons, i, mu.Pt
+		}
+	}
+	for i, ele := range electrons {
+		if !skip(false, i) && ele.Pt > pt {
+			leps, lead, pt = electrons, i, ele.Pt
+		}
+	}
+	if lead < 0 {
+		return fmom.PtEtaPhiM{}, false
+	}
+	return leps.P4(lead), true
+}
+
+// transverseMass returns the transverse mass of the system made of the
+// lepton and the missing transverse momentum (of magnitude metPt and azimuth
+// metPhi).
+func transverseMass(lep fmom.PtEtaPhiM, metPt, metPhi float32) float64 {
+	dphi := lep.Phi() - float64(metPhi)
+	return math.Sqrt(2 * lep.Pt() * float64(metPt) * (1 - math.Cos(dphi)))
+}
+
+// derived8 holds the quantities derived by the 08 analyses from an event,
+// written to their friend trees.
+type derived8 struct {
+	flavour int32    // flavour of the leptons of the best Z candidate: 11 (electrons), 13 (muons), 0 (none)
+	leps    [2]int32 // indices of the leptons of the best Z candidate in their collection (-1 if none)
+	mass    float32  // invariant mass of the best Z candidate
+	lepPt   float32  // pT of the leading other lepton
+	mt      float32  // transverse mass of the MET and the leading other lepton
+}
+
+// newDerived8 returns the quantities derived from an event with a Z
+// candidate made of the leptons i1 and i2 of the muons (if muZ) or of the
+// electrons, and the leading other lepton lep.
+func newDerived8(muZ bool, leps nanoaod.Leptons, i1, i2 int, lep fmom.PtEtaPhiM, mt float64) derived8 {
+	flavour := int32(11)
+	if muZ {
+		flavour = 13
+	}
+	return derived8{
+		flavour: flavour,
+		leps:    [2]int32{int32(i1), int32(i2)},
+		mass:    float32(nanoaod.InvMass(leps, i1, i2)),
+		lepPt:   float32(lep.Pt()),
+		mt:      float32(mt),
+	}
+}
+
+// reset sets the derived quantities to their values for events failing
+// the selection.
+func (d *derived8) reset() {
+	*d = derived8{leps: [2]int32{-1, -1}}
+}
+
+// resetDerived8 returns a slice of n reset derived quantities, reusing the
+// storage of ds if possible.
+func resetDerived8(ds []derived8, n int) []derived8 {
+	if cap(ds) < n {
+		ds = make([]derived8, n)
+	}
+	ds = ds[:n]
+	for i := range ds {
+		ds[i].reset()
+	}
+	return ds
+}
+
+func (d *derived8) vars() []rtree.WriteVar {
+	return []rtree.WriteVar{
+		{Name: "Z_flavour", Value: &d.flavour},
+		{Name: "Z_leptons", Value: &d.leps},
+		{Name: "Z_mass", Value: &d.mass},
+		{Name: "OtherLepton_pt", Value: &d.lepPt},
+		{Name: "MT", Value: &d.mt},
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+func init() {
+	register("08-rsql", "plots the transverse mass of the MET and the leading other lepton in events with a Z candidate and >=3 leptons (rsql)", rsql8)
+}
+
+// rsql8 plots the transverse mass of the MET and the leading other lepton
+// of events with >=3 leptons and a same-flavour opposite-sign lepton pair.
+//
+// SQL: the ">= 3 leptons" cut, on the sum of the lepton multiplicities
+// (WHERE nMuon + nElectron >= 3).
+// Go:  the same-flavour opposite-sign pairs, the leading other lepton and
+// the transverse mass.
+func rsql8() Analysis {
+	return newRSQL(&basic8{}, "nMuon + nElectron >= 3")
+}
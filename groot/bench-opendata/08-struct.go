@@ -0,0 +1,109 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hplot"
+)
+
+func init() {
+	register("08-struct", "plots the transverse mass of the MET and the leading other lepton in events with a Z candidate and >=3 leptons (struct)", func() Analysis { return &struct8{} })
+}
+
+// struct8 runs the following analysis:
+// in events with >=3 leptons and a same-flavour opposite-sign lepton pair,
+// find the best same-flavour opposite-sign lepton pair (mass closest to 91.2 GeV),
+// and plot the transverse mass of the missing energy and the leading other lepton
+type struct8 struct {
+	evt Event
+	z   derived8 // quantities derived from the last processed event (see Derived)
+
+	hmt  *hbook.H1D
+	cuts *CutFlow
+}
+
+func (ana *struct8) Vars() []rtree.ScanVar {
+	return ana.evt.Vars(
+		"Muon_pt", "Muon_eta", "Muon_phi", "Muon_mass", "Muon_charge",
+		"Electron_pt", "Electron_eta", "Electron_phi", "Electron_mass", "Electron_charge",
+		"MET_pt", "MET_phi",
+	)
+}
+
+func (ana *struct8) Derived() []rtree.WriteVar {
+	return ana.z.vars()
+}
+
+func (ana *struct8) Book() []hbook.Histogram {
+	ana.hmt = newH1D("hmt", 100, 0, 200)
+	ana.cuts = newCutFlow(
+		"all events",
+		">= 3 leptons",
+		"same-flavour opposite-sign lepton pair",
+	)
+	return []hbook.Histogram{ana.hmt}
+}
+
+func (ana *struct8) Process() error {
+	evt := &ana.evt
+	evt.Load()
+	ana.z.reset()
+
+	ana.cuts.Fill(0, 1)
+
+	if len(evt.Muons)+len(evt.Electrons) < 3 {
+		return nil
+	}
+	ana.cuts.Fill(1, 1)
+
+	var (
+		imu1, imu2, dmu    = findLeptonPair(evt.Muons)
+		iele1, iele2, dele = findLeptonPair(evt.Electrons)
+	)
+
+	if imu1 < 0 && iele1 < 0 {
+		return nil
+	}
+	ana.cuts.Fill(2, 1)
+
+	var (
+		muZ    = dmu < dele
+		leps   nanoaod.Leptons
+		i1, i2 int
+	)
+	switch {
+	case muZ:
+		leps, i1, i2 = evt.Muons, imu1, imu2
+	default:
+		leps, i1, i2 = evt.Electrons, iele1, iele2
+	}
+	lep, ok := otherLepton(evt.Muons, evt.Electrons, muZ, i1, i2)
+	if !ok {
+		return nil
+	}
+	mt := transverseMass(lep, evt.MET.Pt, evt.MET.Phi)
+
+	ana.hmt.Fill(mt, 1)
+
+	ana.z = newDerived8(muZ, leps, i1, i2, lep, mt)
+	return nil
+}
+
+func (ana *struct8) Plot() Plotter {
+	p := hplot.New()
+	p.X.Label.Text = "Transverse mass [GeV]"
+	p.Y.Label.Text = "Nevts"
+
+	p.Add(hplot.NewH1D(ana.hmt))
+
+	return p
+}
+
+func (ana *struct8) CutFlow() *CutFlow {
+	return ana.cuts
+}
@@ -0,0 +1,277 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// Analysis is an OpenData benchmark analysis.
+//
+// The driver creates the analysis, binds the variables returned by Vars to
+// a scanner over the Events tree, calls Book once, Process once per event and
+// finally Plot and CutFlow to retrieve the plot of the filled histograms and
+// the cut-flow of the event selections.
+type Analysis interface {
+	// Vars returns the list of branches the analysis needs to read,
+	// bound to the addresses the event data will be loaded into.
+	Vars() []rtree.ScanVar
+
+	// Book creates the (named) histograms filled by the analysis,
+	// and its cut-flow.
+	Book() []hbook.Histogram
+
+	// Process analyzes the event currently loaded into the bound variables.
+	Process() error
+
+	// Plot returns the plot of the filled histograms.
+	Plot() Plotter
+
+	// CutFlow returns the cut-flow of the event selections of the analysis.
+	CutFlow() *CutFlow
+}
+
+// TreeAnalysis is an analysis processing the whole Events tree at once,
+// instead of being fed event by event (e.g. an rsql-based analysis.)
+type TreeAnalysis interface {
+	Analysis
+
+	// ProcessTree analyzes the entries of the provided tree selected by
+	// the run context, and returns the number of analyzed entries.
+	// ProcessTree stops early, with the error of the context, when the
+	// context is canceled.
+	ProcessTree(ctx context.Context, t rtree.Tree, rctx RunContext) (int64, error)
+}
+
+// Deriver is an analysis exposing the per-event quantities it derives
+// from the event data (e.g. the indices of its best candidates), so they
+// can be written to a friend tree of the Events tree, entry by entry.
+type Deriver interface {
+	Analysis
+
+	// Derived returns the derived columns, bound to the addresses of the
+	// values computed by Process for the last processed event.
+	// Process sets these values for every event, to default values for
+	// the events failing the selection.
+	Derived() []rtree.WriteVar
+}
+
+// TreeDeriver is a tree analysis exposing the per-event quantities it
+// derives, as a Deriver does.
+// As a tree analysis is not fed event by event, the values bound by
+// Derived are set entry by entry during ProcessTree, which calls the hook
+// registered with OnDerived once the values of each entry are set.
+type TreeDeriver interface {
+	TreeAnalysis
+
+	// Derived returns the derived columns, as Deriver.Derived.
+	// Derived returns no column if the analysis derives no quantity.
+	Derived() []rtree.WriteVar
+
+	// OnDerived registers the function called, during ProcessTree, with
+	// the quantities derived from each processed entry, in order.
+	// ProcessTree stops with the error of the function, if any.
+	// Tree analyses deriving quantities process every entry of the tree,
+	// with no pre-selection.
+	OnDerived(fn func() error)
+}
+
+// RunContext selects the entries of the Events tree processed by the
+// analyses: at most N entries (all the remaining ones if N <= 0), starting
+// at entry Start and taking one entry every Stride entries.
+type RunContext struct {
+	Start  int64 // index of the first entry to process
+	N      int64 // maximum number of entries to process (0: all entries)
+	Stride int64 // distance between two processed entries (0: 1)
+}
+
+// End returns the index (exclusive) of the entry following the last entry
+// to process, for a tree with nentries entries.
+func (ctx RunContext) End(nentries int64) int64 {
+	end := nentries
+	if ctx.N > 0 {
+		if n := ctx.Start + (ctx.N-1)*ctx.stride() + 1; n < end {
+			end = n
+		}
+	}
+	if end < ctx.Start {
+		end = ctx.Start
+	}
+	return end
+}
+
+// Entries returns the number of entries to process, for a tree with
+// nentries entries.
+func (ctx RunContext) Entries(nentries int64) int64 {
+	stride := ctx.stride()
+	return (ctx.End(nentries) - ctx.Start + stride - 1) / stride
+}
+
+// Selects returns whether the i-th entry is to be processed, provided it
+// is before End.
+func (ctx RunContext) Selects(i int64) bool {
+	return i >= ctx.Start && (i-ctx.Start)%ctx.stride() == 0
+}
+
+// IsAll returns whether all the entries of a tree are to be processed.
+func (ctx RunContext) IsAll() bool {
+	return ctx.Start <= 0 && ctx.N <= 0 && ctx.stride() == 1
+}
+
+// split splits the entries to process of a tree with nentries entries into
+// at most n contiguous run contexts of (almost) equal sizes.
+func (ctx RunContext) split(nentries int64, n int) []RunContext {
+	var (
+		ranges = splitRange(0, ctx.Entries(nentries), n)
+		ctxs   = make([]RunContext, len(ranges))
+	)
+	for i, rng := range ranges {
+		ctxs[i] = ctx.slice(rng[0], rng[1])
+	}
+	return ctxs
+}
+
+// chunks splits the entries to process of a chain of trees with the
+// provided numbers of entries into consecutive run contexts of at most
+// every entries (a whole tree if every <= 0), after skipping the first
+// skip entries to process.
+// A chunk never spans two trees.
+func (ctx RunContext) chunks(nentries []int64, every, skip int64) []RunContext {
+	var (
+		ctxs []RunContext
+		off  int64 // index in the chain of the first entry of the tree
+		sel  int64 // number of entries to process before the tree
+	)
+	for _, n := range nentries {
+		var (
+			beg = sel
+			end = sel + ctx.local(off, n).Entries(n)
+		)
+		off += n
+		sel = end
+		if beg < skip {
+			beg = skip
+		}
+		for beg < end {
+			next := end
+			if every > 0 && beg+every < end {
+				next = beg + every
+			}
+			ctxs = append(ctxs, ctx.slice(beg, next))
+			beg = next
+		}
+	}
+	return ctxs
+}
+
+// slice returns the run context selecting the entries [beg, end) of the
+// entries to process selected by ctx, with beg < end.
+func (ctx RunContext) slice(beg, end int64) RunContext {
+	stride := ctx.stride()
+	return RunContext{
+		Start:  ctx.Start + beg*stride,
+		N:      end - beg,
+		Stride: stride,
+	}
+}
+
+// local returns the run context selecting the entries selected by ctx,
+// in a tree holding the entries [off, off+n) of a chain.
+func (ctx RunContext) local(off, n int64) RunContext {
+	var (
+		stride = ctx.stride()
+		beg    = ctx.Start
+		end    = ctx.End(off + n)
+	)
+	if beg < off {
+		beg += (off - beg + stride - 1) / stride * stride
+	}
+	switch {
+	case beg >= end:
+		return RunContext{Start: n, Stride: stride}
+	case beg == off && end == off+n && stride == 1:
+		return RunContext{}
+	}
+	return RunContext{
+		Start:  beg - off,
+		N:      (end - beg + stride - 1) / stride,
+		Stride: stride,
+	}
+}
+
+func (ctx RunContext) stride() int64 {
+	if ctx.Stride < 1 {
+		return 1
+	}
+	return ctx.Stride
+}
+
+// Plotter is a plot that can be drawn and saved to an image file.
+// Plotter is implemented by hplot.Plot and hplot.TiledPlot.
+type Plotter interface {
+	Draw(c draw.Canvas)
+	Save(w, h vg.Length, file string) error
+}
+
+// Bench describes a registered OpenData benchmark.
+type Bench struct {
+	Name string          // name of the benchmark (e.g. "01-basic")
+	Doc  string          // short description of the benchmark
+	New  func() Analysis // creates a new instance of the analysis
+}
+
+// register registers an OpenData benchmark under the provided name.
+// register panics if a benchmark with the same name was already registered.
+func register(name, doc string, fct func() Analysis) *Bench {
+	if _, dup := benchIDs[name]; dup {
+		panic(fmt.Errorf("bench-opendata: duplicate benchmark %q", name))
+	}
+	b := &Bench{Name: name, Doc: doc, New: fct}
+	benchIDs[name] = b
+	return b
+}
+
+// newH1D creates a new named 1-dim histogram.
+func newH1D(name string, n int, xmin, xmax float64) *hbook.H1D {
+	h := hbook.NewH1D(n, xmin, xmax)
+	h.Ann["name"] = name
+	return h
+}
+
+// markPartial marks the histograms of a run interrupted after nevts entries
+// as partial: their annotations record "partial" and the number of
+// "entries", and their title, saved with ROOT histograms, is suffixed with
+// "[partial: nevts entries]".
+func markPartial(hs []hbook.Histogram, nevts int64) {
+	mark := fmt.Sprintf("[partial: %d entries]", nevts)
+	for _, h := range hs {
+		ann := h.Annotation()
+		ann["partial"] = true
+		ann["entries"] = nevts
+		title, _ := ann["title"].(string)
+		if title == "" {
+			title = h.Name()
+		}
+		ann["title"] = title + " " + mark
+	}
+}
+
+// sumW returns the sum of weights of the provided histogram.
+func sumW(h hbook.Histogram) float64 {
+	switch h := h.(type) {
+	case *hbook.H1D:
+		return h.SumW()
+	case *hbook.H2D:
+		return h.SumW()
+	default:
+		panic(fmt.Errorf("bench-opendata: unknown histogram type %T", h))
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRunContext(t *testing.T) {
+	for _, tc := range []struct {
+		ctx     RunContext
+		nevts   int64
+		end     int64
+		entries int64
+		sel     []int64
+	}{
+		{
+			ctx:     RunContext{},
+			nevts:   10,
+			end:     10,
+			entries: 10,
+			sel:     []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+		},
+		{
+			ctx:     RunContext{N: 4},
+			nevts:   10,
+			end:     4,
+			entries: 4,
+			sel:     []int64{0, 1, 2, 3},
+		},
+		{
+			ctx:     RunContext{Start: 2, N: 3, Stride: 3},
+			nevts:   10,
+			end:     9,
+			entries: 3,
+			sel:     []int64{2, 5, 8},
+		},
+		{
+			ctx:     RunContext{Start: 2, N: 5, Stride: 3},
+			nevts:   10,
+			end:     10,
+			entries: 3,
+			sel:     []int64{2, 5, 8},
+		},
+		{
+			ctx:     RunContext{Start: 1, Stride: 4},
+			nevts:   10,
+			end:     10,
+			entries: 3,
+			sel:     []int64{1, 5, 9},
+		},
+		{
+			ctx:     RunContext{Start: 12},
+			nevts:   10,
+			end:     12,
+			entries: 0,
+		},
+	} {
+		t.Run(ctxName(tc.ctx), func(t *testing.T) {
+			if got, want := tc.ctx.End(tc.nevts), tc.end; got != want {
+				t.Fatalf("invalid end: got=%d, want=%d", got, want)
+			}
+			if got, want := tc.ctx.Entries(tc.nevts), tc.entries; got != want {
+				t.Fatalf("invalid number of entries: got=%d, want=%d", got, want)
+			}
+
+			var sel []int64
+			for i := int64(0); i < tc.ctx.End(tc.nevts); i++ {
+				if tc.ctx.Selects(i) {
+					sel = append(sel, i)
+				}
+			}
+			if !reflect.DeepEqual(sel, tc.sel) {
+				t.Fatalf("invalid selected entries:\ngot= %v\nwant=%v", sel, tc.sel)
+			}
+
+			var split []int64
+			for _, ctx := range tc.ctx.split(tc.nevts, 2) {
+				for i := ctx.Start; i < ctx.End(tc.nevts); i++ {
+					if ctx.Selects(i) {
+						split = append(split, i)
+					}
+				}
+			}
+			if !reflect.DeepEqual(split, tc.sel) {
+				t.Fatalf("invalid selected entries after split:\ngot= %v\nwant=%v", split, tc.sel)
+			}
+
+			var chunks []int64
+			for _, ctx := range tc.ctx.chunks([]int64{tc.nevts}, 2, 1) {
+				for i := ctx.Start; i < ctx.End(tc.nevts); i++ {
+					if ctx.Selects(i) {
+						chunks = append(chunks, i)
+					}
+				}
+			}
+			if want := tc.sel; len(want) > 0 && !reflect.DeepEqual(chunks, want[1:]) {
+				t.Fatalf("invalid selected entries after chunks:\ngot= %v\nwant=%v", chunks, want[1:])
+			}
+		})
+	}
+}
+
+func ctxName(ctx RunContext) string {
+	return fmt.Sprintf("start=%d-n=%d-stride=%d", ctx.Start, ctx.N, ctx.Stride)
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-hep/examples/groot/progress"
+	"go-hep.org/x/hep/groot/rarrow"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// arrowChunk is the default number of entries loaded into each ARROW record.
+const arrowChunk = 10000
+
+// frameAnalysis is an analysis processing a whole chunk of entries at once,
+// with vectorised operations over the columns of a frame.
+type frameAnalysis interface {
+	Analysis
+
+	// ProcessFrame analyzes all the entries of the provided frame.
+	ProcessFrame(f *frame) error
+}
+
+// frameDeriver is a frame analysis deriving per-event quantities.
+type frameDeriver interface {
+	frameAnalysis
+
+	// Derived returns the derived columns (see Deriver.)
+	Derived() []rtree.WriteVar
+
+	// deriveFrames makes the following calls to ProcessFrame record the
+	// quantities derived from each entry of their frame.
+	deriveFrames()
+
+	// deriveEntry sets the values bound by Derived to the quantities
+	// derived from the i-th entry of the last processed frame.
+	deriveEntry(i int)
+}
+
+// arrowAnalysis is an analysis reading the Events tree through rarrow.
+//
+// The Events tree is read as a sequence of ARROW records of chunk entries,
+// each of them processed column-wise by the embedded analysis.
+// Records are built from a view of the tree restricted to the branches
+// read by the embedded analysis (see Vars), as rarrow records hold all the
+// branches of their tree.
+//
+// When the embedded analysis is a frameDeriver, the quantities it derives
+// can be written to a friend tree (see OnDerived), entry by entry once
+// each frame is processed.
+type arrowAnalysis struct {
+	frameAnalysis
+
+	chunk   int64        // number of entries per record
+	derived func() error // hook called with the quantities derived from each entry, if any
+}
+
+func newArrow(ana frameAnalysis) *arrowAnalysis {
+	return &arrowAnalysis{frameAnalysis: ana, chunk: arrowChunk}
+}
+
+func (ana *arrowAnalysis) Process() error {
+	return fmt.Errorf("rarrow analyses can only process whole trees")
+}
+
+// Derived returns the columns derived by the embedded analysis, if it is
+// a frameDeriver, and no column otherwise.
+func (ana *arrowAnalysis) Derived() []rtree.WriteVar {
+	d, ok := ana.frameAnalysis.(frameDeriver)
+	if !ok {
+		return nil
+	}
+	return d.Derived()
+}
+
+// OnDerived registers the function called with the quantities derived from
+// each entry of the processed frames.
+// OnDerived is a no-op if the embedded analysis derives no quantity.
+func (ana *arrowAnalysis) OnDerived(fn func() error) {
+	d, ok := ana.frameAnalysis.(frameDeriver)
+	if !ok {
+		return
+	}
+	d.deriveFrames()
+	ana.derived = fn
+}
+
+// ProcessTree processes the entries selected by the run context.
+// With a stride, the selected entries of each record are copied into
+// a frame of their own.
+// The context is checked before each record.
+func (ana *arrowAnalysis) ProcessTree(ctx context.Context, tree rtree.Tree, rctx RunContext) (int64, error) {
+	end := rctx.End(tree.Entries())
+	if end <= rctx.Start {
+		return 0, nil
+	}
+
+	view, err := newBranchView(tree, ana.Vars())
+	if err != nil {
+		return 0, err
+	}
+
+	r := rarrow.NewRecordReader(
+		view,
+		rarrow.WithChunk(ana.chunk),
+		rarrow.WithStart(rctx.Start),
+		rarrow.WithEnd(end),
+	)
+	defer r.Release()
+
+	var (
+		beg = rctx.Start
+		rep = progress.FromContext(ctx)
+		n   int64
+	)
+	for r.Next() {
+		err := ctx.Err()
+		if err != nil {
+			return n, err
+		}
+
+		rec := r.Record()
+		f := newFrame(rec)
+		if rctx.stride() > 1 {
+			var rows []int
+			for i := 0; i < f.Len(); i++ {
+				if rctx.Selects(beg + int64(i)) {
+					rows = append(rows, i)
+				}
+			}
+			f = f.take(rows)
+		}
+		err = ana.ProcessFrame(f)
+		if err != nil {
+			return n, fmt.Errorf("could not process entries [%d, %d): %w", beg, beg+rec.NumRows(), err)
+		}
+		err = ana.derive(f)
+		if err != nil {
+			return n, fmt.Errorf("could not process entries [%d, %d): %w", beg, beg+rec.NumRows(), err)
+		}
+		n += int64(f.Len())
+		rep.Add(int64(f.Len()))
+		beg += rec.NumRows()
+	}
+
+	return n, nil
+}
+
+// derive calls the derived hook with the quantities derived from each entry
+// of the last processed frame.
+func (ana *arrowAnalysis) derive(f *frame) error {
+	if ana.derived == nil {
+		return nil
+	}
+	d := ana.frameAnalysis.(frameDeriver)
+	for i := 0; i < f.Len(); i++ {
+		d.deriveEntry(i)
+		err := ana.derived()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// branchView is a view of a tree restricted to some of its branches.
+type branchView struct {
+	rtree.Tree
+	branches []rtree.Branch
+}
+
+// newBranchView returns a view of the tree restricted to the branches of
+// the provided variables.
+func newBranchView(tree rtree.Tree, vars []rtree.ScanVar) (*branchView, error) {
+	var (
+		view = &branchView{Tree: tree}
+		seen = make(map[string]bool, len(vars))
+	)
+	for _, v := range vars {
+		if seen[v.Name] {
+			continue
+		}
+		seen[v.Name] = true
+		b := tree.Branch(v.Name)
+		if b == nil {
+			return nil, fmt.Errorf("tree %q has no branch %q", tree.Name(), v.Name)
+		}
+		view.branches = append(view.branches, b)
+	}
+	return view, nil
+}
+
+// Branches returns the branches of the view.
+func (t *branchView) Branches() []rtree.Branch {
+	return t.branches
+}
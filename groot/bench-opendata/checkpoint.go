@@ -0,0 +1,249 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/go-hep/examples/groot/progress"
+)
+
+// defaultCheckpointEvery is the default number of entries processed
+// between two checkpoints.
+const defaultCheckpointEvery = 1000000
+
+// checkpointer periodically saves the state of the analyses of a run into
+// checkpoint files, so the run can be resumed after a crash or an
+// interruption.
+//
+// The entries to process are split into consecutive chunks, each of them
+// processed by the same analyses: the histograms of a resumed run are
+// thus filled in the same order, and are identical to the ones of an
+// uninterrupted run.
+type checkpointer struct {
+	dir    string // directory of the checkpoint files
+	every  int64  // maximum number of entries processed between two checkpoints
+	resume bool   // whether to resume runs from their last checkpoint
+}
+
+// checkpoint is the state of the analyses of a run, after a chunk of entries.
+type checkpoint struct {
+	Input   string     // input files (see parseInput)
+	Entries []int64    // number of entries of the Events tree of each input file
+	RunCtx  RunContext // entries of the input files to analyze
+	Next    int64      // number of entries to process already processed
+	Tasks   []taskState
+}
+
+// taskState is the state of an analysis at a checkpoint.
+type taskState struct {
+	Name    string   // name of the benchmark
+	Events  int64    // number of processed events
+	Hists   [][]byte // histograms, in their binary (rio) encoding
+	CutFlow CutFlow
+}
+
+// run processes the entries of the chain selected by cfg.rctx with process,
+// in consecutive chunks of at most ck.every entries, and saves the state of
+// the tasks after each chunk.
+// When resuming, the state of the tasks is first restored from their last
+// checkpoint, and the entries it covers are skipped (and reported as such
+// to the progress reporter of the context.)
+//
+// Checkpoints are not saved anymore once one of the tasks failed.
+// Without checkpointer, run processes all the selected entries at once.
+func (ck *checkpointer) run(ctx context.Context, tasks []*task, ch *chain, cfg config, process func(rctx RunContext) error) error {
+	if ck == nil {
+		return process(cfg.rctx)
+	}
+
+	var (
+		fname = ck.path(tasks)
+		every = ck.every
+		state = checkpoint{
+			Input:  cfg.fname,
+			RunCtx: cfg.rctx,
+		}
+	)
+	for _, f := range ch.inputs() {
+		state.Entries = append(state.Entries, f.entries)
+	}
+	for _, t := range tasks {
+		state.Tasks = append(state.Tasks, taskState{Name: t.bench.Name})
+		if !resumable(t.ana) {
+			every = 0
+		}
+	}
+
+	if ck.resume {
+		err := restore(fname, tasks, &state)
+		if err != nil {
+			return err
+		}
+		progress.FromContext(ctx).Skip(state.Next)
+	}
+
+	for _, chunk := range cfg.rctx.chunks(state.Entries, every, state.Next) {
+		err := process(chunk)
+		if err != nil {
+			return err
+		}
+		if failed(tasks) {
+			if done(tasks) {
+				break
+			}
+			continue
+		}
+
+		state.Next += chunk.N
+		err = save(fname, tasks, &state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// path returns the name of the checkpoint file of the provided tasks.
+func (ck *checkpointer) path(tasks []*task) string {
+	name := "shared"
+	if len(tasks) == 1 {
+		name = tasks[0].bench.Name
+	}
+	return filepath.Join(ck.dir, name+".ckpt")
+}
+
+// resumable returns whether the analysis can start processing a tree from
+// any of its entries, and thus be checkpointed within a tree.
+// rsql analyses always read trees from their first entry: they are only
+// checkpointed after whole trees.
+func resumable(ana Analysis) bool {
+	switch ana.(type) {
+	case *rsqlAnalysis, *rsql1:
+		return false
+	}
+	return true
+}
+
+// failed returns whether one of the tasks failed.
+func failed(tasks []*task) bool {
+	for _, t := range tasks {
+		if t.err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the state of the tasks into the named checkpoint file.
+// The previous checkpoint is only replaced once the new one was
+// completely written.
+func save(fname string, tasks []*task, state *checkpoint) error {
+	for i, t := range tasks {
+		ts := &state.Tasks[i]
+		ts.Events = t.nevts
+		ts.CutFlow = CutFlow{Cuts: append([]Cut(nil), t.ana.CutFlow().Cuts...)}
+		ts.Hists = ts.Hists[:0]
+		for _, h := range t.hs {
+			m, ok := h.(encoding.BinaryMarshaler)
+			if !ok {
+				return fmt.Errorf("could not checkpoint %q: histogram %q can not be marshaled", t.bench.Name, h.Name())
+			}
+			raw, err := m.MarshalBinary()
+			if err != nil {
+				return fmt.Errorf("could not marshal histogram %q of %q: %w", h.Name(), t.bench.Name, err)
+			}
+			ts.Hists = append(ts.Hists, raw)
+		}
+	}
+
+	tmp := fname + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("could not create checkpoint file: %w", err)
+	}
+	defer f.Close()
+
+	err = gob.NewEncoder(f).Encode(state)
+	if err != nil {
+		return fmt.Errorf("could not write checkpoint: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("could not close checkpoint file: %w", err)
+	}
+
+	err = os.Rename(tmp, fname)
+	if err != nil {
+		return fmt.Errorf("could not save checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// restore restores the state of the tasks from the named checkpoint file,
+// which must have been saved by a run of the same analyses over the same
+// entries.
+// Tasks are left untouched when the checkpoint file does not exist.
+func restore(fname string, tasks []*task, state *checkpoint) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("no checkpoint %q: starting from the first entry", fname)
+			return nil
+		}
+		return fmt.Errorf("could not open checkpoint file: %w", err)
+	}
+	defer f.Close()
+
+	var ckpt checkpoint
+	err = gob.NewDecoder(f).Decode(&ckpt)
+	if err != nil {
+		return fmt.Errorf("could not read checkpoint %q: %w", fname, err)
+	}
+
+	switch {
+	case ckpt.Input != state.Input,
+		!reflect.DeepEqual(ckpt.Entries, state.Entries),
+		ckpt.RunCtx != state.RunCtx,
+		len(ckpt.Tasks) != len(tasks):
+		return fmt.Errorf("checkpoint %q was saved by a run over other entries or analyses", fname)
+	}
+
+	for i, t := range tasks {
+		ts := ckpt.Tasks[i]
+		if ts.Name != t.bench.Name || len(ts.Hists) != len(t.hs) {
+			return fmt.Errorf("checkpoint %q was saved by a run over other analyses", fname)
+		}
+		for j, h := range t.hs {
+			u, ok := h.(encoding.BinaryUnmarshaler)
+			if !ok {
+				return fmt.Errorf("could not restore %q: histogram %q can not be unmarshaled", t.bench.Name, h.Name())
+			}
+			err := u.UnmarshalBinary(ts.Hists[j])
+			if err != nil {
+				return fmt.Errorf("could not unmarshal histogram %q of %q: %w", h.Name(), t.bench.Name, err)
+			}
+		}
+		err := t.ana.CutFlow().merge(&ts.CutFlow)
+		if err != nil {
+			return fmt.Errorf("could not restore cut-flow of %q: %w", t.bench.Name, err)
+		}
+		t.nevts = ts.Events
+	}
+	state.Next = ckpt.Next
+
+	log.Printf("resuming from checkpoint %q: %d entries already processed", fname, ckpt.Next)
+	return nil
+}
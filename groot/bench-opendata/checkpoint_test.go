@@ -0,0 +1,205 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+func TestRunContextChunks(t *testing.T) {
+	for _, tc := range []struct {
+		ctx   RunContext
+		every int64
+		skip  int64
+		want  []RunContext
+	}{
+		{
+			ctx:   RunContext{},
+			every: 4,
+			want: []RunContext{
+				{Start: 0, N: 4, Stride: 1},
+				{Start: 4, N: 4, Stride: 1},
+				{Start: 8, N: 2, Stride: 1},
+				{Start: 10, N: 4, Stride: 1},
+				{Start: 14, N: 1, Stride: 1},
+			},
+		},
+		{
+			ctx:   RunContext{},
+			every: 0,
+			skip:  3,
+			want: []RunContext{
+				{Start: 3, N: 7, Stride: 1},
+				{Start: 10, N: 5, Stride: 1},
+			},
+		},
+		{
+			ctx:   RunContext{Start: 1, N: 6, Stride: 2},
+			every: 2,
+			skip:  1,
+			want: []RunContext{
+				{Start: 3, N: 2, Stride: 2},
+				{Start: 7, N: 2, Stride: 2},
+				{Start: 11, N: 1, Stride: 2},
+			},
+		},
+		{
+			ctx:   RunContext{Start: 1, N: 6, Stride: 2},
+			every: 10,
+			skip:  6,
+		},
+	} {
+		t.Run(ctxName(tc.ctx), func(t *testing.T) {
+			got := tc.ctx.chunks([]int64{10, 5}, tc.every, tc.skip)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("invalid chunks:\ngot= %+v\nwant=%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// crashAnalysis is an analysis canceling the run after a number of
+// events, shared by all its instances.
+type crashAnalysis struct {
+	Analysis
+	n      *int64
+	cancel context.CancelFunc
+}
+
+func (ana *crashAnalysis) Process() error {
+	err := ana.Analysis.Process()
+	if atomic.AddInt64(ana.n, -1) == 0 {
+		ana.cancel()
+	}
+	return err
+}
+
+// crashTree is a tree analysis canceling the run after its first tree.
+type crashTree struct {
+	TreeAnalysis
+	cancel context.CancelFunc
+}
+
+func (ana *crashTree) ProcessTree(ctx context.Context, t rtree.Tree, rctx RunContext) (int64, error) {
+	defer ana.cancel()
+	return ana.TreeAnalysis.ProcessTree(ctx, t, rctx)
+}
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"events-1.root", "events-2.root"} {
+		err = createTestFile(filepath.Join(dir, name), 1000)
+		if err != nil {
+			t.Fatalf("could not create input file: %+v", err)
+		}
+	}
+	fname := filepath.Join(dir, "events-*.root")
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		nworkers int
+		rctx     RunContext
+	}{
+		{name: "01-basic", nworkers: 1},
+		{name: "05-struct", nworkers: 1, rctx: RunContext{Start: 10, Stride: 3}},
+		{name: "07-basic", nworkers: 3},
+		{name: "06-arrow", nworkers: 1},
+		{name: "02-rsql", nworkers: 1},
+		{name: "01-rsql", nworkers: 1, rctx: RunContext{N: 1500}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				bench = benchIDs[tc.name]
+				ckpt  = &checkpointer{dir: filepath.Join(dir, tc.name), every: 300}
+				cfg   = config{fname: fname, nworkers: tc.nworkers, rctx: tc.rctx, out: out}
+			)
+			err := os.MkdirAll(ckpt.dir, 0755)
+			if err != nil {
+				t.Fatalf("could not create checkpoint dir: %+v", err)
+			}
+
+			want, wcf, wn, err := run(context.Background(), bench, cfg)
+			if err != nil {
+				t.Fatalf("could not run %q: %+v", tc.name, err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			crash := &Bench{Name: bench.Name, New: func() Analysis {
+				switch ana := bench.New().(type) {
+				case TreeAnalysis:
+					return &crashTree{TreeAnalysis: ana, cancel: cancel}
+				default:
+					n := int64(500)
+					return &crashAnalysis{Analysis: ana, n: &n, cancel: cancel}
+				}
+			}}
+			if tc.nworkers > 1 {
+				n := int64(500)
+				crash.New = func() Analysis {
+					return &crashAnalysis{Analysis: bench.New(), n: &n, cancel: cancel}
+				}
+			}
+
+			cfg.ckpt = ckpt
+			_, _, _, err = run(ctx, crash, cfg)
+			if !interrupted(err) {
+				t.Fatalf("expected an interrupted run, got: %+v", err)
+			}
+			_, err = os.Stat(ckpt.path([]*task{{bench: bench}}))
+			if err != nil {
+				t.Fatalf("no checkpoint saved: %+v", err)
+			}
+
+			ckpt.resume = true
+			for i := 0; i < 2; i++ {
+				// the second time, the run resumes from its last checkpoint,
+				// after all the entries.
+				got, cf, n, err := run(context.Background(), bench, cfg)
+				if err != nil {
+					t.Fatalf("could not resume %q: %+v", tc.name, err)
+				}
+				if n != wn {
+					t.Fatalf("invalid number of processed entries: got=%d, want=%d", n, wn)
+				}
+				if !reflect.DeepEqual(cf, wcf) {
+					t.Fatalf("invalid cut-flow:\ngot= %s\nwant=%s", cutsString(cf), cutsString(wcf))
+				}
+				tol := 0.0
+				if tc.nworkers > 1 {
+					tol = 1e-12
+				}
+				err = cmpHists(got, want, tol)
+				if err != nil {
+					t.Fatalf("resumed and uninterrupted histograms differ: %+v", err)
+				}
+			}
+
+			cfg.rctx.N = 100
+			_, _, _, err = run(context.Background(), bench, cfg)
+			if err == nil {
+				t.Fatalf("expected an error resuming over other entries")
+			}
+		})
+	}
+}
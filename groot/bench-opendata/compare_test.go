@@ -0,0 +1,248 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go-hep.org/x/hep/hbook"
+)
+
+// testEvents is the number of events of the shared input file.
+const testEvents = 2000
+
+// testInput is the input file shared by the tests, generated on first use.
+var testInput struct {
+	once  sync.Once
+	dir   string
+	fname string
+	err   error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testInput.dir != "" {
+		os.RemoveAll(testInput.dir)
+	}
+	os.Exit(code)
+}
+
+// sharedInput returns the path to the input file shared by the tests,
+// holding testEvents generated events.
+// The file must not be modified.
+func sharedInput(t *testing.T) string {
+	t.Helper()
+
+	testInput.once.Do(func() {
+		testInput.dir, testInput.err = ioutil.TempDir("", "bench-opendata-")
+		if testInput.err != nil {
+			return
+		}
+		testInput.fname = filepath.Join(testInput.dir, "events.root")
+		testInput.err = createTestFile(testInput.fname, testEvents)
+	})
+	if testInput.err != nil {
+		t.Fatalf("could not create input file: %+v", testInput.err)
+	}
+	return testInput.fname
+}
+
+// cmpRun describes how a benchmark is run, to be compared with a serial
+// run of its reference over the local input.
+type cmpRun struct {
+	rctx     RunContext
+	nworkers int   // number of concurrent workers
+	chunk    int64 // number of entries per ARROW record (0: arrowChunk)
+	remote   bool  // whether the input is read through a XRootD server
+}
+
+func (r cmpRun) String() string {
+	return fmt.Sprintf("%s-j=%d-chunk=%d-remote=%v", ctxName(r.rctx), r.nworkers, r.chunk, r.remote)
+}
+
+// cmpResult holds the results of a benchmark run.
+type cmpResult struct {
+	hs    []hbook.Histogram
+	cuts  *CutFlow
+	nevts int64
+}
+
+// TestCompare checks that the results of the benchmarks do not depend on
+// the way their analysis is written (struct, arrow, rsql), nor on the way
+// they are run (in parallel, over XRootD).
+func TestCompare(t *testing.T) {
+	fname := sharedInput(t)
+
+	srv := newXrdServer(t, filepath.Dir(fname))
+	defer srv.Close()
+
+	// chains of 2 files, to read over XRootD.
+	var (
+		local  = fname + "," + fname
+		remote = srv.url(filepath.Base(fname)) + "," + srv.url(filepath.Base(fname))
+	)
+
+	var (
+		suffix = func(suffix string) func(name string) bool {
+			return func(name string) bool { return strings.HasSuffix(name, suffix) }
+		}
+		basic = func(name string) string {
+			return name[:strings.LastIndex(name, "-")] + "-basic"
+		}
+		self = func(name string) string { return name }
+	)
+
+	for _, tc := range []struct {
+		name  string                   // name of the comparison
+		bench func(name string) bool   // whether the benchmark is compared
+		ref   func(name string) string // reference benchmark of a compared benchmark
+		runs  []cmpRun                 // compared runs of the benchmark
+		tol   float64                  // relative tolerance of the histograms moments
+	}{
+		{
+			name:  "struct",
+			bench: suffix("-struct"),
+			ref:   basic,
+			runs:  []cmpRun{{}},
+			tol:   1e-9,
+		},
+		{
+			name:  "arrow",
+			bench: suffix("-arrow"),
+			ref:   basic,
+			runs: []cmpRun{
+				{},
+				{chunk: 300},
+				{rctx: RunContext{N: 500}, chunk: 300},
+				{rctx: RunContext{N: 5000}, chunk: 1},
+				{rctx: RunContext{Start: 250, N: 700, Stride: 3}, chunk: 300},
+				{rctx: RunContext{Start: 1001, Stride: 7}},
+			},
+			tol: 1e-9,
+		},
+		{
+			name:  "rsql",
+			bench: suffix("-rsql"),
+			ref:   basic,
+			runs: []cmpRun{
+				{},
+				{rctx: RunContext{N: 500}},
+				{rctx: RunContext{Start: 100, N: 300, Stride: 7}},
+			},
+			// rsqldrv may convert float32 columns to float64
+			// differently than a plain Go conversion.
+			tol: 1e-6,
+		},
+		{
+			name: "parallel",
+			bench: func(name string) bool {
+				_, ok := benchIDs[name].New().(TreeAnalysis)
+				return !ok
+			},
+			ref: self,
+			runs: []cmpRun{
+				{nworkers: 4},
+				{nworkers: 4, rctx: RunContext{Start: 123, N: 1500, Stride: 2}},
+			},
+			tol: 1e-9,
+		},
+		{
+			name: "xrootd",
+			bench: func(name string) bool {
+				switch name {
+				case "01-basic", "05-basic", "05-struct", "05-arrow", "05-rsql":
+					return true
+				}
+				return false
+			},
+			ref: self,
+			runs: []cmpRun{
+				{remote: true},
+				{remote: true, nworkers: 3},
+				{remote: true, rctx: RunContext{Start: 500, N: 1000, Stride: 3}},
+			},
+			tol: 1e-12,
+		},
+	} {
+		for _, name := range sortedBenchNames() {
+			if !tc.bench(name) {
+				continue
+			}
+			bench := benchIDs[name]
+			ref, ok := benchIDs[tc.ref(name)]
+			if !ok {
+				t.Errorf("no reference for %q", name)
+				continue
+			}
+			for _, r := range tc.runs {
+				r := r
+				tol := tc.tol
+				t.Run(tc.name+"/"+name+"-"+r.String(), func(t *testing.T) {
+					input := fname
+					if r.remote {
+						input = local
+					}
+					want, err := runCmp(ref, config{fname: input, rctx: r.rctx}, 0)
+					if err != nil {
+						t.Fatalf("could not run %q: %+v", ref.Name, err)
+					}
+
+					if r.remote {
+						input = remote
+					}
+					beg := atomic.LoadInt64(&bytesRead)
+					got, err := runCmp(bench, config{fname: input, nworkers: r.nworkers, rctx: r.rctx}, r.chunk)
+					if err != nil {
+						t.Fatalf("could not run %q: %+v", bench.Name, err)
+					}
+					if r.remote && atomic.LoadInt64(&bytesRead) == beg {
+						t.Fatalf("no bytes read over XRootD")
+					}
+
+					if got.nevts != want.nevts {
+						t.Fatalf("invalid number of processed entries: got=%d, want=%d", got.nevts, want.nevts)
+					}
+					if got, want := cutsString(got.cuts), cutsString(want.cuts); got != want {
+						t.Fatalf("invalid cut-flow:\ngot= %s\nwant=%s", got, want)
+					}
+					err = cmpHists(got.hs, want.hs, tol)
+					if err != nil {
+						t.Fatalf("%s and %s histograms differ: %+v", bench.Name, ref.Name, err)
+					}
+				})
+			}
+		}
+	}
+}
+
+// runCmp runs the benchmark with the provided configuration, and ARROW
+// records of chunk entries (if not zero) for arrow analyses.
+func runCmp(bench *Bench, cfg config, chunk int64) (cmpResult, error) {
+	ctx := context.Background()
+	switch ana := bench.New().(type) {
+	case TreeAnalysis:
+		if ana, ok := ana.(*arrowAnalysis); ok && chunk > 0 {
+			ana.chunk = chunk
+		}
+		t := &task{bench: bench, ana: ana, hs: ana.Book()}
+		err := processTree(ctx, t, cfg)
+		return cmpResult{hs: t.hs, cuts: ana.CutFlow(), nevts: t.nevts}, err
+	default:
+		tasks, err := runTasks(ctx, []*Bench{bench}, cfg)
+		if err != nil {
+			return cmpResult{}, err
+		}
+		t := tasks[0]
+		return cmpResult{hs: t.hs, cuts: t.ana.CutFlow(), nevts: t.nevts}, t.err
+	}
+}
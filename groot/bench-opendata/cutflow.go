@@ -0,0 +1,99 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// CutFlow records the events passing each of the successive selections
+// of an analysis.
+// By convention, the first cut selects all the events given to the analysis.
+type CutFlow struct {
+	Cuts []Cut
+}
+
+// Cut describes the events passing a selection, and all the previous ones.
+type Cut struct {
+	Name string  // description of the selection
+	N    int64   // number of events passing the selection
+	SumW float64 // sum of weights of the events passing the selection
+}
+
+// newCutFlow creates a new cut-flow with the provided, ordered, cuts.
+func newCutFlow(names ...string) *CutFlow {
+	cf := &CutFlow{Cuts: make([]Cut, len(names))}
+	for i, name := range names {
+		cf.Cuts[i].Name = name
+	}
+	return cf
+}
+
+// Fill records an event with weight w passing the i-th cut.
+func (cf *CutFlow) Fill(i int, w float64) {
+	c := &cf.Cuts[i]
+	c.N++
+	c.SumW += w
+}
+
+// fillN records n events with weight w passing the i-th cut.
+func (cf *CutFlow) fillN(i int, n int64, w float64) {
+	c := &cf.Cuts[i]
+	c.N += n
+	c.SumW += float64(n) * w
+}
+
+// Eff returns the (weighted) efficiency of the i-th cut, relative to
+// the previous one.
+func (cf *CutFlow) Eff(i int) float64 {
+	if i == 0 {
+		return ratio(cf.Cuts[0].SumW, cf.Cuts[0].SumW)
+	}
+	return ratio(cf.Cuts[i].SumW, cf.Cuts[i-1].SumW)
+}
+
+// CumEff returns the (weighted) efficiency of the first i+1 cuts.
+func (cf *CutFlow) CumEff(i int) float64 {
+	return ratio(cf.Cuts[i].SumW, cf.Cuts[0].SumW)
+}
+
+func ratio(num, den float64) float64 {
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
+
+// merge adds the events recorded by src to the cut-flow.
+func (cf *CutFlow) merge(src *CutFlow) error {
+	if len(cf.Cuts) != len(src.Cuts) {
+		return fmt.Errorf("cut-flow length mismatch (%d != %d)", len(cf.Cuts), len(src.Cuts))
+	}
+	for i := range cf.Cuts {
+		d := &cf.Cuts[i]
+		s := &src.Cuts[i]
+		if d.Name != s.Name {
+			return fmt.Errorf("cut name mismatch (%q != %q)", d.Name, s.Name)
+		}
+		d.N += s.N
+		d.SumW += s.SumW
+	}
+	return nil
+}
+
+// writeTable writes the cut-flow as a table to w.
+func (cf *CutFlow) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "cut\tevents\tsumw\teff\tcum. eff\n")
+	for i, c := range cf.Cuts {
+		fmt.Fprintf(
+			tw, "%s\t%d\t%g\t%.2f%%\t%.2f%%\n",
+			c.Name, c.N, c.SumW, 100*cf.Eff(i), 100*cf.CumEff(i),
+		)
+	}
+	return tw.Flush()
+}
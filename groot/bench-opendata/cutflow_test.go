@@ -0,0 +1,135 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCutFlow(t *testing.T) {
+	cf := newCutFlow("all events", "cut-1", "cut-2")
+	for i := 0; i < 10; i++ {
+		cf.Fill(0, 2)
+		if i%2 == 0 {
+			cf.Fill(1, 2)
+			if i%4 == 0 {
+				cf.Fill(2, 2)
+			}
+		}
+	}
+
+	o := newCutFlow("all events", "cut-1", "cut-2")
+	o.fillN(0, 10, 2)
+	o.fillN(1, 5, 2)
+	err := cf.merge(o)
+	if err != nil {
+		t.Fatalf("could not merge cut-flows: %+v", err)
+	}
+
+	for i, want := range []Cut{
+		{Name: "all events", N: 20, SumW: 40},
+		{Name: "cut-1", N: 10, SumW: 20},
+		{Name: "cut-2", N: 3, SumW: 6},
+	} {
+		if got := cf.Cuts[i]; got != want {
+			t.Fatalf("invalid cut %d:\ngot= %+v\nwant=%+v", i, got, want)
+		}
+	}
+
+	if got, want := cf.Eff(2), 0.3; got != want {
+		t.Fatalf("invalid efficiency: got=%v, want=%v", got, want)
+	}
+	if got, want := cf.CumEff(2), 0.15; got != want {
+		t.Fatalf("invalid cumulative efficiency: got=%v, want=%v", got, want)
+	}
+
+	o = newCutFlow("all events", "cut-2", "cut-1")
+	err = cf.merge(o)
+	if err == nil {
+		t.Fatalf("expected an error merging different cut-flows")
+	}
+
+	var buf strings.Builder
+	err = cf.writeTable(&buf)
+	if err != nil {
+		t.Fatalf("could not write cut-flow: %+v", err)
+	}
+	want := `cut         events  sumw  eff      cum. eff
+all events  20      40    100.00%  100.00%
+cut-1       10      20    50.00%   50.00%
+cut-2       3       6     30.00%   15.00%
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("invalid cut-flow table:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestCutFlows checks that all the flavours of an analysis (basic, struct,
+// arrow and rsql) record the same cut-flow, whether run serially, in
+// parallel or over a subset of the entries.
+func TestCutFlows(t *testing.T) {
+	for _, ctx := range []RunContext{
+		{},
+		{Start: 100, N: 500, Stride: 3},
+	} {
+		for _, name := range sortedBenchNames() {
+			bench := benchIDs[name]
+			ref := benchIDs[strings.SplitN(name, "-", 2)[0]+"-basic"]
+			ctx := ctx
+			t.Run(name+"-"+ctxName(ctx), func(t *testing.T) {
+				want, err := runCutFlow(ref, config{fname: goldenInput, rctx: ctx})
+				if err != nil {
+					t.Fatalf("could not run %q: %+v", ref.Name, err)
+				}
+
+				if got, want := want.Cuts[0].N, ctx.Entries(goldenEvents); got != want {
+					t.Fatalf("invalid number of events: got=%d, want=%d", got, want)
+				}
+				for i := 1; i < len(want.Cuts); i++ {
+					if want.Cuts[i].N > want.Cuts[i-1].N {
+						t.Fatalf("cut %q selects more events than the previous one", want.Cuts[i].Name)
+					}
+				}
+
+				for _, nworkers := range []int{1, 3} {
+					got, err := runCutFlow(bench, config{fname: goldenInput, rctx: ctx, nworkers: nworkers})
+					if err != nil {
+						t.Fatalf("could not run %q: %+v", name, err)
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Fatalf("invalid cut-flow (workers=%d):\ngot= %s\nwant=%s", nworkers, cutsString(got), cutsString(want))
+					}
+				}
+			})
+		}
+	}
+}
+
+// runCutFlow runs the analysis over the input files and returns its
+// cut-flow.
+func runCutFlow(bench *Bench, cfg config) (*CutFlow, error) {
+	if ana, ok := bench.New().(TreeAnalysis); ok {
+		err := processTree(context.Background(), &task{bench: bench, ana: ana, hs: ana.Book()}, cfg)
+		return ana.CutFlow(), err
+	}
+
+	tasks, err := runTasks(context.Background(), []*Bench{bench}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return tasks[0].ana.CutFlow(), tasks[0].err
+}
+
+func cutsString(cf *CutFlow) string {
+	var o []string
+	for _, c := range cf.Cuts {
+		o = append(o, fmt.Sprintf("%s=%d", c.Name, c.N))
+	}
+	return "[" + strings.Join(o, ", ") + "]"
+}
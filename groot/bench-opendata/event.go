@@ -0,0 +1,109 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// Event is a Run2012B NanoAOD event.
+//
+// Event is filled from the columns of the Events tree, bound to a scanner
+// through the ScanVars returned by Vars.
+// Only the fields of the objects whose branches were requested are filled,
+// the other ones are left to their zero value.
+type Event struct {
+	Muons     nanoaod.Muons
+	Electrons nanoaod.Electrons
+	Jets      nanoaod.Jets
+	MET       MET
+
+	raw struct {
+		muons     leptonCols
+		electrons leptonCols
+		jets      jetCols
+	}
+}
+
+// MET is the NanoAOD missing transverse energy of an event.
+type MET struct {
+	Pt    float32
+	Phi   float32
+	SumEt float32
+}
+
+type leptonCols struct {
+	pt     []float32
+	eta    []float32
+	phi    []float32
+	mass   []float32
+	charge []int32
+}
+
+type jetCols struct {
+	pt   []float32
+	eta  []float32
+	phi  []float32
+	mass []float32
+	btag []float32
+}
+
+// Vars returns the ScanVars binding the named branches of the Events tree
+// to the event.
+// Vars panics if a branch is not part of the Event schema.
+func (evt *Event) Vars(names ...string) []rtree.ScanVar {
+	var (
+		mus  = &evt.raw.muons
+		eles = &evt.raw.electrons
+		jets = &evt.raw.jets
+		met  = &evt.MET
+	)
+	ptrs := map[string]interface{}{
+		"Muon_pt":         &mus.pt,
+		"Muon_eta":        &mus.eta,
+		"Muon_phi":        &mus.phi,
+		"Muon_mass":       &mus.mass,
+		"Muon_charge":     &mus.charge,
+		"Electron_pt":     &eles.pt,
+		"Electron_eta":    &eles.eta,
+		"Electron_phi":    &eles.phi,
+		"Electron_mass":   &eles.mass,
+		"Electron_charge": &eles.charge,
+		"Jet_pt":          &jets.pt,
+		"Jet_eta":         &jets.eta,
+		"Jet_phi":         &jets.phi,
+		"Jet_mass":        &jets.mass,
+		"Jet_btag":        &jets.btag,
+		"MET_pt":          &met.Pt,
+		"MET_phi":         &met.Phi,
+		"MET_sumet":       &met.SumEt,
+	}
+
+	vars := make([]rtree.ScanVar, len(names))
+	for i, name := range names {
+		ptr, ok := ptrs[name]
+		if !ok {
+			panic(fmt.Errorf("bench-opendata: no branch %q in Event schema", name))
+		}
+		vars[i] = rtree.ScanVar{Name: name, Value: ptr}
+	}
+	return vars
+}
+
+// Load fills the event's collections with the columns read from
+// the Events tree.
+func (evt *Event) Load() {
+	var (
+		mus  = &evt.raw.muons
+		eles = &evt.raw.electrons
+		jets = &evt.raw.jets
+	)
+	evt.Muons.Load(mus.pt, mus.eta, mus.phi, mus.mass, mus.charge)
+	evt.Electrons.Load(eles.pt, eles.eta, eles.phi, eles.mass, eles.charge)
+	evt.Jets.Load(jets.pt, jets.eta, jets.phi, jets.mass, jets.btag)
+}
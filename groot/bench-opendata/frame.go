@@ -0,0 +1,456 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow/go/arrow/array"
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/gonum/stat/combin"
+)
+
+// frame is a chunk of entries of the Events tree, stored column-wise.
+//
+// Flat columns are exposed as Go slices with one element per entry.
+// Jagged columns are exposed as a flat slice of the elements of all the
+// entries, together with the offsets delimiting the elements of each entry.
+// Both share the memory of the underlying ARROW record, unless the frame
+// only holds a subset of the entries of the record (see take.)
+type frame struct {
+	rec  array.Record
+	cols map[string]int
+	rows []int // entries of the record held by the frame (nil: all entries)
+}
+
+func newFrame(rec array.Record) *frame {
+	f := &frame{
+		rec:  rec,
+		cols: make(map[string]int, rec.NumCols()),
+	}
+	for i := range rec.Columns() {
+		f.cols[rec.ColumnName(i)] = i
+	}
+	return f
+}
+
+// take returns a frame holding the provided entries of the frame record.
+// Columns of the returned frame are copied out of the record.
+func (f *frame) take(rows []int) *frame {
+	if rows == nil {
+		rows = []int{}
+	}
+	return &frame{rec: f.rec, cols: f.cols, rows: rows}
+}
+
+// Len returns the number of entries of the frame.
+func (f *frame) Len() int {
+	if f.rows != nil {
+		return len(f.rows)
+	}
+	return int(f.rec.NumRows())
+}
+
+func (f *frame) column(name string) array.Interface {
+	i, ok := f.cols[name]
+	if !ok {
+		panic(fmt.Errorf("bench-opendata: no column %q in frame", name))
+	}
+	return f.rec.Column(i)
+}
+
+// Float32s returns the named flat float32 column.
+func (f *frame) Float32s(name string) []float32 {
+	arr, ok := f.column(name).(*array.Float32)
+	if !ok {
+		panic(fmt.Errorf("bench-opendata: column %q is not a float32 column", name))
+	}
+	vs := arr.Float32Values()
+	if f.rows == nil {
+		return vs
+	}
+
+	o := make([]float32, len(f.rows))
+	for i, row := range f.rows {
+		o[i] = vs[row]
+	}
+	return o
+}
+
+// Jagged returns the named jagged float32 column.
+func (f *frame) Jagged(name string) jagged {
+	offs, vals := f.list(name)
+	arr, ok := vals.(*array.Float32)
+	if !ok {
+		panic(fmt.Errorf("bench-opendata: column %q is not a jagged float32 column", name))
+	}
+	vs := arr.Float32Values()
+	if f.rows == nil {
+		return jagged{offs: offs, vals: vs}
+	}
+
+	offs, idx := f.takeList(offs)
+	o := make([]float32, len(idx))
+	for i, j := range idx {
+		o[i] = vs[j]
+	}
+	return jagged{offs: offs, vals: o}
+}
+
+// JaggedI32 returns the named jagged int32 column.
+func (f *frame) JaggedI32(name string) jaggedI32 {
+	offs, vals := f.list(name)
+	arr, ok := vals.(*array.Int32)
+	if !ok {
+		panic(fmt.Errorf("bench-opendata: column %q is not a jagged int32 column", name))
+	}
+	vs := arr.Int32Values()
+	if f.rows == nil {
+		return jaggedI32{offs: offs, vals: vs}
+	}
+
+	offs, idx := f.takeList(offs)
+	o := make([]int32, len(idx))
+	for i, j := range idx {
+		o[i] = vs[j]
+	}
+	return jaggedI32{offs: offs, vals: o}
+}
+
+func (f *frame) list(name string) ([]int32, array.Interface) {
+	arr, ok := f.column(name).(*array.List)
+	if !ok {
+		panic(fmt.Errorf("bench-opendata: column %q is not a jagged column", name))
+	}
+	return arr.Offsets(), arr.ListValues()
+}
+
+// takeList returns the offsets of the elements of the entries held by the
+// frame, for a jagged column of the frame record with the provided offsets,
+// together with the indices of these elements into the column values.
+func (f *frame) takeList(offs []int32) ([]int32, []int32) {
+	var (
+		o   = make([]int32, 1, len(f.rows)+1)
+		idx []int32
+	)
+	for _, row := range f.rows {
+		for i := offs[row]; i < offs[row+1]; i++ {
+			idx = append(idx, i)
+		}
+		o = append(o, int32(len(idx)))
+	}
+	return o, idx
+}
+
+// jagged is a jagged float32 column.
+type jagged struct {
+	offs []int32   // offsets of the elements of each entry into vals
+	vals []float32 // elements of all entries
+}
+
+// At returns the elements of the i-th entry.
+func (j jagged) At(i int) []float32 { return j.vals[j.offs[i]:j.offs[i+1]] }
+
+// jaggedI32 is a jagged int32 column.
+type jaggedI32 struct {
+	offs []int32
+	vals []int32
+}
+
+// At returns the elements of the i-th entry.
+func (j jaggedI32) At(i int) []int32 { return j.vals[j.offs[i]:j.offs[i+1]] }
+
+// mask selects the elements of a column.
+type mask []bool
+
+// all returns a mask selecting all of the n elements of a column.
+func all(n int) mask {
+	o := make(mask, n)
+	for i := range o {
+		o[i] = true
+	}
+	return o
+}
+
+// and returns the element-wise conjunction of the provided masks.
+func and(m1, m2 mask) mask {
+	o := make(mask, len(m1))
+	for i := range o {
+		o[i] = m1[i] && m2[i]
+	}
+	return o
+}
+
+// gt returns the mask selecting the values above x.
+func gt(vs []float32, x float32) mask {
+	o := make(mask, len(vs))
+	for i, v := range vs {
+		o[i] = v > x
+	}
+	return o
+}
+
+// absLt returns the mask selecting the values with an absolute value below x.
+func absLt(vs []float32, x float64) mask {
+	o := make(mask, len(vs))
+	for i, v := range vs {
+		o[i] = math.Abs(float64(v)) < x
+	}
+	return o
+}
+
+// within returns the mask selecting the values in the open interval (lo, hi).
+func within(vs []float64, lo, hi float64) mask {
+	o := make(mask, len(vs))
+	for i, v := range vs {
+		o[i] = lo < v && v < hi
+	}
+	return o
+}
+
+// absDiff returns the absolute difference between each value and x.
+func absDiff(vs []float64, x float64) []float64 {
+	o := make([]float64, len(vs))
+	for i, v := range vs {
+		o[i] = math.Abs(v - x)
+	}
+	return o
+}
+
+// sizes returns the number of elements of each entry.
+func sizes(offs []int32) []int {
+	o := make([]int, len(offs)-1)
+	for i := range o {
+		o[i] = int(offs[i+1] - offs[i])
+	}
+	return o
+}
+
+// count returns the number of selected elements of each entry.
+func count(offs []int32, sel mask) []int {
+	o := make([]int, len(offs)-1)
+	for i := range o {
+		for _, ok := range sel[offs[i]:offs[i+1]] {
+			if ok {
+				o[i]++
+			}
+		}
+	}
+	return o
+}
+
+// atLeast returns the mask selecting the entries with at least n elements.
+func atLeast(ns []int, n int) mask {
+	o := make(mask, len(ns))
+	for i, v := range ns {
+		o[i] = v >= n
+	}
+	return o
+}
+
+// sum returns the sum of the selected elements of each entry.
+func sum(offs []int32, vs []float32, sel mask) []float64 {
+	o := make([]float64, len(offs)-1)
+	for i := range o {
+		for j := offs[i]; j < offs[i+1]; j++ {
+			if sel[j] {
+				o[i] += float64(vs[j])
+			}
+		}
+	}
+	return o
+}
+
+// argLeading returns the index, into the flat elements of the column, of
+// the largest selected element of each entry, or -1 if there is none.
+func argLeading(offs []int32, vs []float32, sel mask) []int32 {
+	o := make([]int32, len(offs)-1)
+	for i := range o {
+		o[i] = -1
+		var max float32
+		for j := offs[i]; j < offs[i+1]; j++ {
+			if sel[j] && vs[j] > max {
+				o[i] = j
+				max = vs[j]
+			}
+		}
+	}
+	return o
+}
+
+// minDeltaR2 returns, for each element of the first jagged collection, the
+// smallest squared distance in (eta, phi) to the selected elements of the
+// second collection in the same entry (+Inf if there is none.)
+func minDeltaR2(eta1, phi1, eta2, phi2 jagged, sel2 mask) []float64 {
+	const twopi = 2 * math.Pi
+
+	o := make([]float64, len(eta1.vals))
+	for i := 0; i < len(eta1.offs)-1; i++ {
+		for i1 := eta1.offs[i]; i1 < eta1.offs[i+1]; i1++ {
+			dr2 := math.Inf(+1)
+			for i2 := eta2.offs[i]; i2 < eta2.offs[i+1]; i2++ {
+				if !sel2[i2] {
+					continue
+				}
+				dphi := -math.Remainder(float64(phi1.vals[i1]-phi2.vals[i2]), twopi)
+				deta := float64(eta1.vals[i1] - eta2.vals[i2])
+				dr2 = math.Min(dphi*dphi+deta*deta, dr2)
+			}
+			o[i1] = dr2
+		}
+	}
+	return o
+}
+
+// fill fills the histogram with the selected values.
+// All the values are used if sel is nil.
+func fill(h *hbook.H1D, vs []float32, sel mask) {
+	for i, v := range vs {
+		if sel != nil && !sel[i] {
+			continue
+		}
+		h.Fill(float64(v), 1)
+	}
+}
+
+// fillF64 fills the histogram with the selected values.
+func fillF64(h *hbook.H1D, vs []float64, sel mask) {
+	for i, v := range vs {
+		if sel[i] {
+			h.Fill(v, 1)
+		}
+	}
+}
+
+// fillCut records the selected entries as passing the i-th cut of the
+// cut-flow, with a unit weight.
+func fillCut(cf *CutFlow, i int, sel mask) {
+	for _, ok := range sel {
+		if ok {
+			cf.Fill(i, 1)
+		}
+	}
+}
+
+// combs holds the k-combinations of the elements of each entry of a jagged
+// column.
+type combs struct {
+	offs []int32   // offsets of the combinations of each entry into idx
+	idx  [][]int32 // idx[j][n] is the index of the j-th element of the n-th combination
+}
+
+// combinations returns the k-combinations of the elements of each entry
+// of a jagged column, as indices into the flat elements of the column.
+// Combinations are generated in the order of combin.Combinations.
+func combinations(offs []int32, k int) combs {
+	var (
+		o = combs{
+			offs: make([]int32, len(offs)),
+			idx:  make([][]int32, k),
+		}
+		cache = make(map[int][][]int)
+	)
+	for i := 0; i < len(offs)-1; i++ {
+		beg := offs[i]
+		n := int(offs[i+1] - beg)
+		if n >= k {
+			cs, ok := cache[n]
+			if !ok {
+				cs = combin.Combinations(n, k)
+				cache[n] = cs
+			}
+			for _, c := range cs {
+				for j, v := range c {
+					o.idx[j] = append(o.idx[j], beg+int32(v))
+				}
+			}
+		}
+		o.offs[i+1] = int32(len(o.idx[0]))
+	}
+	return o
+}
+
+// Len returns the total number of combinations.
+func (c combs) Len() int { return len(c.idx[0]) }
+
+// Mass returns the invariant mass of each combination of particles.
+func (c combs) Mass(pt, eta, phi, mass []float32) []float64 {
+	o := make([]float64, c.Len())
+	for n := range o {
+		p := c.Sum(n, pt, eta, phi, mass)
+		o[n] = p.M()
+	}
+	return o
+}
+
+// Sum returns the sum of the 4-momenta of the particles of the n-th
+// combination.
+func (c combs) Sum(n int, pt, eta, phi, mass []float32) fmom.PtEtaPhiM {
+	p4 := func(i int32) *fmom.PtEtaPhiM {
+		p := fmom.NewPtEtaPhiM(float64(pt[i]), float64(eta[i]), float64(phi[i]), float64(mass[i]))
+		return &p
+	}
+	var sum fmom.P4 = p4(c.idx[0][n])
+	for _, idx := range c.idx[1:] {
+		sum = fmom.Add(sum, p4(idx[n]))
+	}
+	return *sum.(*fmom.PtEtaPhiM)
+}
+
+// OppositeCharge returns the mask selecting the pairs of particles
+// with opposite charges.
+func (c combs) OppositeCharge(charge []int32) mask {
+	var (
+		o  = make(mask, c.Len())
+		i1 = c.idx[0]
+		i2 = c.idx[1]
+	)
+	for n := range o {
+		o[n] = charge[i1[n]] != charge[i2[n]]
+	}
+	return o
+}
+
+// Any returns the mask selecting the entries with at least one selected
+// combination.
+func (c combs) Any(sel mask) mask {
+	o := make(mask, len(c.offs)-1)
+	for i := range o {
+		for _, ok := range sel[c.offs[i]:c.offs[i+1]] {
+			if ok {
+				o[i] = true
+				break
+			}
+		}
+	}
+	return o
+}
+
+// ArgMin returns, for each entry, the index of the selected combination
+// with the smallest value, or -1 if there is none.
+// The smallest value of each entry is also returned (math.MaxFloat64 if none.)
+// All the combinations are considered if sel is nil.
+func (c combs) ArgMin(vs []float64, sel mask) ([]int, []float64) {
+	var (
+		idx  = make([]int, len(c.offs)-1)
+		mins = make([]float64, len(idx))
+	)
+	for i := range idx {
+		idx[i] = -1
+		mins[i] = math.MaxFloat64
+		for n := int(c.offs[i]); n < int(c.offs[i+1]); n++ {
+			if sel != nil && !sel[n] {
+				continue
+			}
+			if vs[n] < mins[i] {
+				mins[i] = vs[n]
+				idx[i] = n
+			}
+		}
+	}
+	return idx, mins
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/go-hep/examples/groot/bench-opendata/friend"
+)
+
+// friendFile is a ROOT file where the quantities derived by analyses are
+// written, into friend trees of the Events trees under a directory per
+// analysis.
+type friendFile struct {
+	*treeFile
+}
+
+// newFriendFile creates the named friend file.
+func newFriendFile(fname string) (*friendFile, error) {
+	tf, err := createTreeFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("could not create friend file: %w", err)
+	}
+	return &friendFile{treeFile: tf}, nil
+}
+
+// Close closes the friend file.
+func (ff *friendFile) Close() error {
+	if ff == nil {
+		return nil
+	}
+	return ff.treeFile.Close()
+}
+
+// friendWriter writes the quantities derived by an analysis from each
+// entry of the Events trees into a friend tree: the i-th entry of the
+// friend tree holds the quantities derived from the i-th entry of the
+// chain of Events trees.
+type friendWriter struct {
+	w *treeWriter
+}
+
+// newFriendWriter creates the friend writer of the provided task, writing
+// the columns derived by its analysis into the <benchmark>/Friend tree of
+// the friend file.
+// newFriendWriter returns nil if ff is nil or if the analysis does not
+// derive any quantity.
+// The friend writer of a TreeDeriver writes the quantities derived from
+// each entry through its OnDerived hook.
+func (ff *friendFile) newFriendWriter(t *task) (*friendWriter, error) {
+	if ff == nil {
+		return nil, nil
+	}
+
+	ana, ok := t.ana.(Deriver)
+	if !ok || len(ana.Derived()) == 0 {
+		log.Printf("%q derives no quantity: no friend tree", t.bench.Name)
+		return nil, nil
+	}
+
+	title := "quantities derived by " + t.bench.Name
+	w, err := ff.newWriter(t.bench, friend.TreeName, title, ana.Derived())
+	if err != nil {
+		return nil, fmt.Errorf("could not create friend tree of %q: %w", t.bench.Name, err)
+	}
+
+	fw := &friendWriter{w: w}
+	if ana, ok := ana.(TreeDeriver); ok {
+		ana.OnDerived(fw.write)
+	}
+	return fw, nil
+}
+
+// write writes the quantities derived from the last processed entry.
+func (fw *friendWriter) write() error {
+	if fw == nil {
+		return nil
+	}
+
+	err := fw.w.write()
+	if err != nil {
+		return fmt.Errorf("could not write friend entry: %w", err)
+	}
+	return nil
+}
+
+// close closes the friend tree, and records its provenance: the source
+// files, the analysis and the number of written entries.
+func (fw *friendWriter) close(bench *Bench, cfg config, err error) error {
+	if fw == nil {
+		return nil
+	}
+
+	var nevts int64
+	for _, f := range cfg.inputs {
+		nevts += f.entries
+	}
+	lines := []string{
+		fmt.Sprintf("analysis: %s (%s)", bench.Name, bench.Doc),
+		fmt.Sprintf("entries: %d of %d entries", fw.w.n, nevts),
+	}
+	if interrupted(err) {
+		lines = append(lines, fmt.Sprintf("partial: %v", err))
+	}
+
+	cerr := fw.w.close(cfg, lines...)
+	if cerr != nil {
+		return fmt.Errorf("could not close friend tree of %q: %w", bench.Name, cerr)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package friend reads the Events trees of OpenData files together with
+// the friend trees of per-event quantities derived by the bench-opendata
+// analyses (see the -friend flag of bench-opendata).
+//
+// The i-th entry of a friend tree holds the quantities derived from the
+// i-th entry of the chain of Events trees it was written from, so later
+// studies can read them instead of recomputing them.
+package friend // import "github.com/go-hep/examples/groot/bench-opendata/friend"
+
+import (
+	"fmt"
+
+	"go-hep.org/x/hep/groot/rbase"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// TreeName is the name of the friend tree of an analysis, under the
+// directory of the analysis.
+const TreeName = "Friend"
+
+// Get returns the friend tree written by the named analysis into the
+// provided directory, and its provenance: the input files it is aligned
+// with, and its number of entries.
+func Get(dir riofs.Directory, bench string) (rtree.Tree, string, error) {
+	o, err := dir.Get(bench)
+	if err != nil {
+		return nil, "", fmt.Errorf("friend: could not find directory of %q: %w", bench, err)
+	}
+	bdir, ok := o.(riofs.Directory)
+	if !ok {
+		return nil, "", fmt.Errorf("friend: %q is not a directory (%T)", bench, o)
+	}
+
+	o, err = bdir.Get(TreeName)
+	if err != nil {
+		return nil, "", fmt.Errorf("friend: could not find friend tree of %q: %w", bench, err)
+	}
+	tree, ok := o.(rtree.Tree)
+	if !ok {
+		return nil, "", fmt.Errorf("friend: %s/%s is not a tree (%T)", bench, TreeName, o)
+	}
+
+	o, err = bdir.Get("provenance")
+	if err != nil {
+		return nil, "", fmt.Errorf("friend: could not find provenance of %q: %w", bench, err)
+	}
+	prov, ok := o.(*rbase.ObjString)
+	if !ok {
+		return nil, "", fmt.Errorf("friend: invalid provenance of %q (%T)", bench, o)
+	}
+
+	return tree, prov.String(), nil
+}
+
+// Scanner scans the Events trees of a run and their friend tree together,
+// entry by entry.
+// Each variable is read from the trees holding its branch.
+type Scanner struct {
+	evts  []rtree.Tree
+	offs  []int64 // index of the first entry of each Events tree
+	evars []rtree.ScanVar
+
+	cur   int            // index of the Events tree of the current entry
+	esc   *rtree.Scanner // scanner of the current Events tree, if any
+	fsc   *rtree.Scanner // scanner of the friend tree, if any
+	entry int64          // index of the next entry to load
+	n     int64          // total number of entries
+
+	err error
+}
+
+// NewScanner creates a scanner over the entries of the Events trees of a
+// run (one per input file, in the order of the run) and of their friend
+// tree, loading the provided variables.
+// Each variable must name a branch of either the Events trees or the
+// friend tree, not both, and the friend tree must have as many entries as
+// the Events trees.
+//
+// Events trees are scanned one after the other rather than as a
+// rtree.Chain, which can not scan jagged branches in go-hep v0.24.1.
+func NewScanner(events []rtree.Tree, friend rtree.Tree, vars ...rtree.ScanVar) (*Scanner, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("friend: no Events tree")
+	}
+	if len(vars) == 0 {
+		return nil, fmt.Errorf("friend: no variable to scan")
+	}
+
+	sc := &Scanner{
+		evts: events,
+		offs: make([]int64, len(events)),
+		cur:  -1,
+	}
+	for i, t := range events {
+		sc.offs[i] = sc.n
+		sc.n += t.Entries()
+	}
+	if sc.n != friend.Entries() {
+		return nil, fmt.Errorf(
+			"friend: trees %q and %q are not aligned: %d and %d entries",
+			events[0].Name(), friend.Name(), sc.n, friend.Entries(),
+		)
+	}
+
+	var fvars []rtree.ScanVar
+	for _, v := range vars {
+		var (
+			inEvts   = events[0].Branch(v.Name) != nil
+			inFriend = friend.Branch(v.Name) != nil
+		)
+		switch {
+		case inEvts && inFriend:
+			return nil, fmt.Errorf("friend: ambiguous branch %q: found in %q and %q", v.Name, events[0].Name(), friend.Name())
+		case inEvts:
+			sc.evars = append(sc.evars, v)
+		case inFriend:
+			fvars = append(fvars, v)
+		default:
+			return nil, fmt.Errorf("friend: no branch %q in %q nor %q", v.Name, events[0].Name(), friend.Name())
+		}
+	}
+
+	if len(fvars) > 0 {
+		var err error
+		sc.fsc, err = rtree.NewScannerVars(friend, fvars...)
+		if err != nil {
+			return nil, fmt.Errorf("friend: could not create scanner of %q: %w", friend.Name(), err)
+		}
+	}
+
+	return sc, nil
+}
+
+// Close closes the scanner.
+func (sc *Scanner) Close() error {
+	var err error
+	for _, s := range []*rtree.Scanner{sc.esc, sc.fsc} {
+		if s == nil {
+			continue
+		}
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	sc.esc = nil
+	sc.fsc = nil
+	return err
+}
+
+// Err returns the error, if any, encountered during the scan.
+func (sc *Scanner) Err() error {
+	if sc.err != nil {
+		return sc.err
+	}
+	for _, s := range []*rtree.Scanner{sc.esc, sc.fsc} {
+		if s == nil {
+			continue
+		}
+		if err := s.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Entry returns the index of the current entry, in the chain of Events
+// trees.
+func (sc *Scanner) Entry() int64 {
+	return sc.entry - 1
+}
+
+// SeekEntry points the scanner to the i-th entry, ready to be scanned
+// after a call to Next.
+func (sc *Scanner) SeekEntry(i int64) error {
+	if i < 0 || i >= sc.n {
+		return fmt.Errorf("friend: entry %d out of range [0, %d)", i, sc.n)
+	}
+	sc.entry = i
+	if sc.fsc != nil {
+		err := sc.fsc.SeekEntry(i)
+		if err != nil {
+			return err
+		}
+	}
+	return sc.seekEvents(i)
+}
+
+// seekEvents points the scanner of the Events tree holding the i-th entry
+// to that entry, creating it if needed.
+func (sc *Scanner) seekEvents(i int64) error {
+	if len(sc.evars) == 0 {
+		return nil
+	}
+
+	cur := len(sc.offs) - 1
+	for cur > 0 && sc.offs[cur] > i {
+		cur--
+	}
+	if cur != sc.cur || sc.esc == nil {
+		if sc.esc != nil {
+			_ = sc.esc.Close()
+			sc.esc = nil
+		}
+		esc, err := rtree.NewScannerVars(sc.evts[cur], sc.evars...)
+		if err != nil {
+			return fmt.Errorf("friend: could not create scanner of Events tree #%d: %w", cur, err)
+		}
+		sc.cur = cur
+		sc.esc = esc
+	}
+
+	return sc.esc.SeekEntry(i - sc.offs[cur])
+}
+
+// Next prepares the next entry for reading with the Scan method.
+// It returns false when there are no more entries or when an error
+// occurred.
+func (sc *Scanner) Next() bool {
+	if sc.err != nil || sc.entry >= sc.n {
+		return false
+	}
+
+	if len(sc.evars) > 0 && (sc.esc == nil || sc.entry == sc.offs[sc.cur]+sc.evts[sc.cur].Entries()) {
+		err := sc.seekEvents(sc.entry)
+		if err != nil {
+			sc.err = err
+			return false
+		}
+	}
+
+	for _, s := range []*rtree.Scanner{sc.esc, sc.fsc} {
+		if s == nil {
+			continue
+		}
+		if !s.Next() {
+			return false
+		}
+	}
+	sc.entry++
+	return true
+}
+
+// Scan loads the current entry of the Events and friend trees into the
+// bound variables.
+func (sc *Scanner) Scan() error {
+	if sc.err != nil {
+		return sc.err
+	}
+	for _, s := range []*rtree.Scanner{sc.esc, sc.fsc} {
+		if s == nil {
+			continue
+		}
+		err := s.Scan()
+		if err != nil {
+			sc.err = err
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package friend
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rbase"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+func TestScanner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "friend-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// entry i of the Events trees holds i%4 jets of pT i, and its
+	// friend entry the index i and the sum of the jets pT.
+	var (
+		sizes  = []int{7, 0, 10, 5}
+		events []rtree.Tree
+		off    int
+	)
+	for i, n := range sizes {
+		fname := filepath.Join(dir, fmt.Sprintf("events-%d.root", i))
+		beg := off
+		f, tree := createTree(t, fname, "Events", n, func(i int) map[string]interface{} {
+			pt := make([]float32, (beg+i)%4)
+			for j := range pt {
+				pt[j] = float32(beg + i)
+			}
+			return map[string]interface{}{"nJet": int32(len(pt)), "Jet_pt": pt}
+		})
+		defer f.Close()
+		events = append(events, tree)
+		off += n
+	}
+
+	fname := filepath.Join(dir, "friend.root")
+	f, friend := createTree(t, fname, TreeName, off, func(i int) map[string]interface{} {
+		return map[string]interface{}{"Entry": int64(i), "Jet_sumpt": float32(i * (i % 4))}
+	})
+	defer f.Close()
+
+	var (
+		pt    []float32
+		entry int64
+		sumpt float32
+	)
+	sc, err := NewScanner(events, friend,
+		rtree.ScanVar{Name: "Jet_pt", Value: &pt},
+		rtree.ScanVar{Name: "Entry", Value: &entry},
+		rtree.ScanVar{Name: "Jet_sumpt", Value: &sumpt},
+	)
+	if err != nil {
+		t.Fatalf("could not create scanner: %+v", err)
+	}
+	defer sc.Close()
+
+	check := func(beg int64) {
+		t.Helper()
+		n := beg
+		for sc.Next() {
+			err := sc.Scan()
+			if err != nil {
+				t.Fatalf("could not scan entry %d: %+v", sc.Entry(), err)
+			}
+			if sc.Entry() != n || entry != n {
+				t.Fatalf("invalid entry: got=%d (friend=%d), want=%d", sc.Entry(), entry, n)
+			}
+			var sum float32
+			for _, v := range pt {
+				if v != float32(n) {
+					t.Fatalf("entry %d: invalid jets pT: %v", n, pt)
+				}
+				sum += v
+			}
+			if len(pt) != int(n%4) || sum != sumpt {
+				t.Fatalf("entry %d: misaligned trees: pt=%v, sumpt=%v", n, pt, sumpt)
+			}
+			n++
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatalf("could not scan trees: %+v", err)
+		}
+		if n != int64(off) {
+			t.Fatalf("invalid number of scanned entries: got=%d, want=%d", n-beg, int64(off)-beg)
+		}
+	}
+
+	check(0)
+	for _, i := range []int64{3, 7, 17, 21} {
+		err = sc.SeekEntry(i)
+		if err != nil {
+			t.Fatalf("could not seek to entry %d: %+v", i, err)
+		}
+		check(i)
+	}
+
+	err = sc.SeekEntry(int64(off))
+	if err == nil {
+		t.Fatalf("expected an error seeking past the last entry")
+	}
+
+	// only reading the friend tree.
+	fsc, err := NewScanner(events, friend, rtree.ScanVar{Name: "Entry", Value: &entry})
+	if err != nil {
+		t.Fatalf("could not create friend scanner: %+v", err)
+	}
+	defer fsc.Close()
+	n := 0
+	for fsc.Next() {
+		n++
+	}
+	if n != off {
+		t.Fatalf("invalid number of friend entries: got=%d, want=%d", n, off)
+	}
+}
+
+func TestScannerErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "friend-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f1, events := createTree(t, filepath.Join(dir, "events.root"), "Events", 10, func(i int) map[string]interface{} {
+		return map[string]interface{}{"MET_pt": float32(i)}
+	})
+	defer f1.Close()
+
+	f2, friend := createTree(t, filepath.Join(dir, "friend.root"), TreeName, 10, func(i int) map[string]interface{} {
+		return map[string]interface{}{"MET_pt": float32(i), "Z_mass": float32(i)}
+	})
+	defer f2.Close()
+
+	f3, short := createTree(t, filepath.Join(dir, "short.root"), TreeName, 9, func(i int) map[string]interface{} {
+		return map[string]interface{}{"Z_mass": float32(i)}
+	})
+	defer f3.Close()
+
+	var v float32
+	for _, tc := range []struct {
+		name   string
+		events []rtree.Tree
+		friend rtree.Tree
+		vars   []rtree.ScanVar
+		err    string
+	}{
+		{
+			name:   "no-events",
+			friend: friend,
+			vars:   []rtree.ScanVar{{Name: "Z_mass", Value: &v}},
+			err:    "friend: no Events tree",
+		},
+		{
+			name:   "no-vars",
+			events: []rtree.Tree{events},
+			friend: friend,
+			err:    "friend: no variable to scan",
+		},
+		{
+			name:   "misaligned",
+			events: []rtree.Tree{events},
+			friend: short,
+			vars:   []rtree.ScanVar{{Name: "Z_mass", Value: &v}},
+			err:    `friend: trees "Events" and "Friend" are not aligned: 10 and 9 entries`,
+		},
+		{
+			name:   "ambiguous",
+			events: []rtree.Tree{events},
+			friend: friend,
+			vars:   []rtree.ScanVar{{Name: "MET_pt", Value: &v}},
+			err:    `friend: ambiguous branch "MET_pt": found in "Events" and "Friend"`,
+		},
+		{
+			name:   "missing",
+			events: []rtree.Tree{events},
+			friend: friend,
+			vars:   []rtree.ScanVar{{Name: "Jet_pt", Value: &v}},
+			err:    `friend: no branch "Jet_pt" in "Events" nor "Friend"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewScanner(tc.events, tc.friend, tc.vars...)
+			switch {
+			case err == nil:
+				t.Fatalf("expected an error")
+			case err.Error() != tc.err:
+				t.Fatalf("invalid error:\ngot= %v\nwant=%s", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "friend-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "friends.root")
+	f, err := groot.Create(fname)
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+
+	for _, name := range []string{"06-basic", "08-basic"} {
+		bdir, err := riofs.Dir(f).Mkdir(name)
+		if err != nil {
+			t.Fatalf("could not create directory: %+v", err)
+		}
+		var v float32
+		w, err := rtree.NewWriter(bdir, TreeName, []rtree.WriteVar{{Name: "Z_mass", Value: &v}})
+		if err != nil {
+			t.Fatalf("could not create tree: %+v", err)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatalf("could not close tree: %+v", err)
+		}
+		if name == "08-basic" {
+			continue
+		}
+		err = bdir.Put("provenance", rbase.NewObjString("source: events.root\n"))
+		if err != nil {
+			t.Fatalf("could not write provenance: %+v", err)
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+
+	f, err = groot.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	tree, prov, err := Get(f, "06-basic")
+	if err != nil {
+		t.Fatalf("could not get friend tree: %+v", err)
+	}
+	if tree.Name() != TreeName || prov != "source: events.root\n" {
+		t.Fatalf("invalid friend tree: name=%q, provenance=%q", tree.Name(), prov)
+	}
+
+	for _, tc := range []struct {
+		bench string
+		err   string
+	}{
+		{bench: "01-basic", err: `friend: could not find directory of "01-basic"`},
+		{bench: "08-basic", err: `friend: could not find provenance of "08-basic"`},
+	} {
+		_, _, err := Get(f, tc.bench)
+		switch {
+		case err == nil:
+			t.Fatalf("%s: expected an error", tc.bench)
+		case !strings.HasPrefix(err.Error(), tc.err):
+			t.Fatalf("%s: invalid error:\ngot= %v\nwant=%s", tc.bench, err, tc.err)
+		}
+	}
+}
+
+// createTree creates the named file, holding a tree of n entries whose
+// values are returned by fill, and returns the tree read back from the
+// file.
+// The branches of the tree are the ones of the first entry: a jagged
+// branch Foo_bar is counted by the int32 branch nFoo.
+func createTree(t *testing.T, fname, name string, n int, fill func(i int) map[string]interface{}) (*riofs.File, rtree.Tree) {
+	t.Helper()
+
+	f, err := groot.Create(fname)
+	if err != nil {
+		t.Fatalf("could not create file: %+v", err)
+	}
+
+	var (
+		vars  = fill(0)
+		names []string
+		ptrs  = make(map[string]reflect.Value)
+		wvars []rtree.WriteVar
+	)
+	for k := range vars {
+		names = append(names, k)
+	}
+	// count branches first.
+	sort.Slice(names, func(i, j int) bool {
+		ci, cj := !strings.Contains(names[i], "_"), !strings.Contains(names[j], "_")
+		if ci != cj {
+			return ci
+		}
+		return names[i] < names[j]
+	})
+	for _, k := range names {
+		ptr := reflect.New(reflect.TypeOf(vars[k]))
+		ptrs[k] = ptr
+		var count string
+		if ptr.Elem().Kind() == reflect.Slice {
+			count = "n" + k[:strings.Index(k, "_")]
+		}
+		wvars = append(wvars, rtree.WriteVar{Name: k, Value: ptr.Interface(), Count: count})
+	}
+
+	w, err := rtree.NewWriter(f, name, wvars)
+	if err != nil {
+		t.Fatalf("could not create tree: %+v", err)
+	}
+	for i := 0; i < n; i++ {
+		for k, v := range fill(i) {
+			ptrs[k].Elem().Set(reflect.ValueOf(v))
+		}
+		_, err = w.Write()
+		if err != nil {
+			t.Fatalf("could not write entry %d: %+v", i, err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("could not close tree: %+v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		t.Fatalf("could not close file: %+v", err)
+	}
+
+	f, err = groot.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	o, err := f.Get(name)
+	if err != nil {
+		t.Fatalf("could not get tree: %+v", err)
+	}
+	return f, o.(rtree.Tree)
+}
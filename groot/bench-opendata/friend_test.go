@@ -0,0 +1,409 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-hep/examples/groot/bench-opendata/friend"
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+func TestFriend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var fnames []string
+	for i, n := range []int{1500, 700} {
+		fname := filepath.Join(dir, fmt.Sprintf("events-%d.root", i+1))
+		err = createTestFile(fname, n)
+		if err != nil {
+			t.Fatalf("could not create input file: %+v", err)
+		}
+		fnames = append(fnames, fname)
+	}
+	input := strings.Join(fnames, ",")
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	oname := filepath.Join(dir, "friends.root")
+	ff, err := newFriendFile(oname)
+	if err != nil {
+		t.Fatalf("could not create friend file: %+v", err)
+	}
+
+	ch, err := openTree(input)
+	if err != nil {
+		t.Fatalf("could not open input files: %+v", err)
+	}
+	defer ch.Close()
+
+	cfg := config{fname: input, inputs: ch.inputs(), out: out, friend: ff}
+	variants := []string{"struct", "arrow", "rsql"}
+	for _, name := range []string{
+		"06-basic", "06-struct", "06-arrow", "06-rsql",
+		"08-basic", "08-struct", "08-arrow", "08-rsql",
+		"01-basic", "01-rsql",
+	} {
+		_, _, _, err := run(context.Background(), benchIDs[name], cfg)
+		if err != nil {
+			t.Fatalf("could not run %q: %+v", name, err)
+		}
+	}
+	err = ff.Close()
+	if err != nil {
+		t.Fatalf("could not close friend file: %+v", err)
+	}
+
+	f, err := groot.Open(oname)
+	if err != nil {
+		t.Fatalf("could not open friend file: %+v", err)
+	}
+	defer f.Close()
+
+	for _, name := range []string{"01-basic", "01-rsql"} {
+		if _, _, err := friend.Get(f, name); err == nil {
+			t.Fatalf("unexpected friend tree for %s", name)
+		}
+	}
+
+	var nevts int64
+	for _, tree := range ch.trees {
+		nevts += tree.Entries()
+	}
+
+	t.Run("06-basic", func(t *testing.T) {
+		tree, prov := getFriend(t, f, "06-basic", nevts)
+		if want := "file: " + fnames[1] + " (700 entries)\n"; !strings.Contains(prov, want) {
+			t.Fatalf("invalid provenance:\ngot:\n%s\nwant: %q", prov, want)
+		}
+
+		var (
+			pt, eta, phi, mass, btag []float32
+			jets                     nanoaod.Jets
+
+			tri             [3]int32
+			triPt, triMass  float32
+			triBtag         float32
+			ntri, nfriended int
+		)
+		sc, err := friend.NewScanner(ch.trees, tree,
+			rtree.ScanVar{Name: "Jet_pt", Value: &pt},
+			rtree.ScanVar{Name: "Jet_eta", Value: &eta},
+			rtree.ScanVar{Name: "Jet_phi", Value: &phi},
+			rtree.ScanVar{Name: "Jet_mass", Value: &mass},
+			rtree.ScanVar{Name: "Jet_btag", Value: &btag},
+			rtree.ScanVar{Name: "Trijet_jets", Value: &tri},
+			rtree.ScanVar{Name: "Trijet_pt", Value: &triPt},
+			rtree.ScanVar{Name: "Trijet_mass", Value: &triMass},
+			rtree.ScanVar{Name: "Trijet_btag", Value: &triBtag},
+		)
+		if err != nil {
+			t.Fatalf("could not create scanner: %+v", err)
+		}
+		defer sc.Close()
+
+		for sc.Next() {
+			err := sc.Scan()
+			if err != nil {
+				t.Fatalf("could not scan entry %d: %+v", sc.Entry(), err)
+			}
+			nfriended++
+
+			if len(pt) < 3 {
+				if tri != [3]int32{-1, -1, -1} || triPt != 0 || triMass != 0 || triBtag != 0 {
+					t.Fatalf("entry %d: invalid derived quantities without trijet: %v %v %v %v", sc.Entry(), tri, triPt, triMass, triBtag)
+				}
+				continue
+			}
+			ntri++
+
+			jets.Load(pt, eta, phi, mass, btag)
+			idx := findTriJet(jets)
+			want := [3]int32{int32(idx[0]), int32(idx[1]), int32(idx[2])}
+			if tri != want {
+				t.Fatalf("entry %d: invalid trijet: got=%v, want=%v", sc.Entry(), tri, want)
+			}
+			p := nanoaod.Sum(jets, idx[:]...)
+			if got, want := float64(triPt), p.Pt(); !closeTo(got, want) {
+				t.Fatalf("entry %d: invalid trijet pT: got=%v, want=%v", sc.Entry(), got, want)
+			}
+			if got, want := float64(triMass), p.M(); !closeTo(got, want) {
+				t.Fatalf("entry %d: invalid trijet mass: got=%v, want=%v", sc.Entry(), got, want)
+			}
+			var wbtag float32
+			for _, i := range idx {
+				if btag[i] > wbtag {
+					wbtag = btag[i]
+				}
+			}
+			if triBtag != wbtag {
+				t.Fatalf("entry %d: invalid trijet b-tag: got=%v, want=%v", sc.Entry(), triBtag, wbtag)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatalf("could not scan trees: %+v", err)
+		}
+		if nfriended != int(nevts) || ntri == 0 {
+			t.Fatalf("invalid number of scanned entries: %d (%d with a trijet)", nfriended, ntri)
+		}
+	})
+
+	t.Run("08-basic", func(t *testing.T) {
+		tree, _ := getFriend(t, f, "08-basic", nevts)
+
+		var (
+			muPt, muEta, muPhi, muMass     []float32
+			elePt, eleEta, elePhi, eleMass []float32
+			muCharge, eleCharge            []int32
+			metPt, metPhi                  float32
+			muons                          nanoaod.Muons
+			electrons                      nanoaod.Electrons
+
+			flavour int32
+			leps    [2]int32
+			zMass   float32
+			lepPt   float32
+			mt      float32
+			nz      int
+		)
+		sc, err := friend.NewScanner(ch.trees, tree,
+			rtree.ScanVar{Name: "Muon_pt", Value: &muPt},
+			rtree.ScanVar{Name: "Muon_eta", Value: &muEta},
+			rtree.ScanVar{Name: "Muon_phi", Value: &muPhi},
+			rtree.ScanVar{Name: "Muon_mass", Value: &muMass},
+			rtree.ScanVar{Name: "Muon_charge", Value: &muCharge},
+			rtree.ScanVar{Name: "Electron_pt", Value: &elePt},
+			rtree.ScanVar{Name: "Electron_eta", Value: &eleEta},
+			rtree.ScanVar{Name: "Electron_phi", Value: &elePhi},
+			rtree.ScanVar{Name: "Electron_mass", Value: &eleMass},
+			rtree.ScanVar{Name: "Electron_charge", Value: &eleCharge},
+			rtree.ScanVar{Name: "MET_pt", Value: &metPt},
+			rtree.ScanVar{Name: "MET_phi", Value: &metPhi},
+			rtree.ScanVar{Name: "Z_flavour", Value: &flavour},
+			rtree.ScanVar{Name: "Z_leptons", Value: &leps},
+			rtree.ScanVar{Name: "Z_mass", Value: &zMass},
+			rtree.ScanVar{Name: "OtherLepton_pt", Value: &lepPt},
+			rtree.ScanVar{Name: "MT", Value: &mt},
+		)
+		if err != nil {
+			t.Fatalf("could not create scanner: %+v", err)
+		}
+		defer sc.Close()
+
+		// re-read entries from the middle of the second file.
+		err = sc.SeekEntry(1600)
+		if err != nil {
+			t.Fatalf("could not seek: %+v", err)
+		}
+		for sc.Next() {
+			err := sc.Scan()
+			if err != nil {
+				t.Fatalf("could not scan entry %d: %+v", sc.Entry(), err)
+			}
+
+			muons.Load(muPt, muEta, muPhi, muMass, muCharge)
+			electrons.Load(elePt, eleEta, elePhi, eleMass, eleCharge)
+			var (
+				imu1, imu2, dmu    = findLeptonPair(muons)
+				iele1, iele2, dele = findLeptonPair(electrons)
+			)
+			var (
+				wflavour int32
+				wleps    = [2]int32{-1, -1}
+				wmass    float64
+				wlep     fmom.PtEtaPhiM
+			)
+			switch {
+			case len(muPt)+len(elePt) < 3, imu1 < 0 && iele1 < 0:
+			case dmu < dele:
+				wflavour, wleps = 13, [2]int32{int32(imu1), int32(imu2)}
+				wmass = nanoaod.InvMass(muons, imu1, imu2)
+				wlep, _ = otherLepton(muons, electrons, true, imu1, imu2)
+			default:
+				wflavour, wleps = 11, [2]int32{int32(iele1), int32(iele2)}
+				wmass = nanoaod.InvMass(electrons, iele1, iele2)
+				wlep, _ = otherLepton(muons, electrons, false, iele1, iele2)
+			}
+			if flavour != wflavour || leps != wleps || !closeTo(float64(zMass), wmass) {
+				t.Fatalf(
+					"entry %d: invalid Z candidate: got=(%d, %v, %v), want=(%d, %v, %v)",
+					sc.Entry(), flavour, leps, zMass, wflavour, wleps, wmass,
+				)
+			}
+			if wflavour == 0 {
+				if lepPt != 0 || mt != 0 {
+					t.Fatalf("entry %d: invalid derived quantities without Z candidate: %v %v", sc.Entry(), lepPt, mt)
+				}
+				continue
+			}
+			nz++
+
+			if got, want := float64(lepPt), wlep.Pt(); got != want {
+				t.Fatalf("entry %d: invalid other lepton pT: got=%v, want=%v", sc.Entry(), got, want)
+			}
+			if got, want := float64(mt), transverseMass(wlep, metPt, metPhi); !closeTo(got, want) {
+				t.Fatalf("entry %d: invalid transverse mass: got=%v, want=%v", sc.Entry(), got, want)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatalf("could not scan trees: %+v", err)
+		}
+		if nz == 0 {
+			t.Fatalf("no Z candidate")
+		}
+	})
+
+	for _, id := range []string{"06", "08"} {
+		ref := id + "-basic"
+		for _, v := range variants {
+			name := id + "-" + v
+			t.Run(name, func(t *testing.T) {
+				cmpFriends(t, f, name, ref, nevts)
+			})
+		}
+	}
+}
+
+func TestFriendErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		nworkers int
+		rctx     RunContext
+		err      string
+	}{
+		{name: "06-basic", nworkers: 2, err: "friend trees can not be written by concurrent workers"},
+		{name: "08-basic", rctx: RunContext{Stride: 2}, err: "friend trees require processing all the entries"},
+		{name: "08-basic", rctx: RunContext{N: 10}, err: "friend trees require processing all the entries"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ff, err := newFriendFile(filepath.Join(dir, "friends.root"))
+			if err != nil {
+				t.Fatalf("could not create friend file: %+v", err)
+			}
+			defer ff.Close()
+
+			cfg := config{fname: goldenInput, nworkers: tc.nworkers, rctx: tc.rctx, out: out, friend: ff}
+			_, _, _, err = run(context.Background(), benchIDs[tc.name], cfg)
+			switch {
+			case err == nil:
+				t.Fatalf("expected an error")
+			case err.Error() != tc.err:
+				t.Fatalf("invalid error:\ngot= %v\nwant=%s", err, tc.err)
+			}
+		})
+	}
+}
+
+// getFriend returns the friend tree of the named benchmark, checking it
+// has the expected number of entries, and its provenance.
+func getFriend(t *testing.T, dir riofs.Directory, bench string, nevts int64) (rtree.Tree, string) {
+	t.Helper()
+
+	tree, prov, err := friend.Get(dir, bench)
+	if err != nil {
+		t.Fatalf("could not retrieve friend tree: %+v", err)
+	}
+	if got := tree.Entries(); got != nevts {
+		t.Fatalf("invalid number of friend entries: got=%d, want=%d", got, nevts)
+	}
+	if want := fmt.Sprintf("entries: %d of %d entries\n", nevts, nevts); !strings.Contains(prov, want) {
+		t.Fatalf("invalid provenance:\ngot:\n%s\nwant: %q", prov, want)
+	}
+	return tree, prov
+}
+
+// cmpFriends checks that the named benchmark derived, from each entry, the
+// same quantities as its reference benchmark.
+func cmpFriends(t *testing.T, dir riofs.Directory, name, ref string, nevts int64) {
+	t.Helper()
+
+	scan := func(name string) (*rtree.Scanner, []rtree.WriteVar) {
+		tree, _ := getFriend(t, dir, name, nevts)
+		wvars := benchIDs[ref].New().(Deriver).Derived()
+		svars := make([]rtree.ScanVar, len(wvars))
+		for i, v := range wvars {
+			svars[i] = rtree.ScanVar{Name: v.Name, Value: v.Value}
+		}
+		sc, err := rtree.NewScannerVars(tree, svars...)
+		if err != nil {
+			t.Fatalf("could not create scanner of %q: %+v", name, err)
+		}
+		return sc, wvars
+	}
+
+	var (
+		gsc, got  = scan(name)
+		wsc, want = scan(ref)
+	)
+	defer gsc.Close()
+	defer wsc.Close()
+
+	for wsc.Next() {
+		if !gsc.Next() {
+			t.Fatalf("missing entry %d: %+v", wsc.Entry(), gsc.Err())
+		}
+		for _, sc := range []*rtree.Scanner{gsc, wsc} {
+			err := sc.Scan()
+			if err != nil {
+				t.Fatalf("could not scan entry %d: %+v", sc.Entry(), err)
+			}
+		}
+		for i, w := range want {
+			var (
+				gv = reflect.ValueOf(got[i].Value).Elem().Interface()
+				wv = reflect.ValueOf(w.Value).Elem().Interface()
+				ok bool
+			)
+			switch wv := wv.(type) {
+			case float32:
+				ok = closeTo(float64(gv.(float32)), float64(wv))
+			default:
+				ok = reflect.DeepEqual(gv, wv)
+			}
+			if !ok {
+				t.Fatalf("entry %d: invalid %s: got=%v, want=%v", wsc.Entry(), w.Name, gv, wv)
+			}
+		}
+	}
+	if err := wsc.Err(); err != nil {
+		t.Fatalf("could not scan %q: %+v", ref, err)
+	}
+}
+
+// closeTo returns whether a derived quantity stored as a float32 matches
+// its float64 recomputation.
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-6*math.Max(1, math.Abs(want))
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command gen-opendata generates a ROOT file with synthetic NanoAOD-like
+// events, that can be analyzed offline by bench-opendata.
+//
+// Usage: gen-opendata [options]
+//
+// ex:
+//
+//	$> gen-opendata -o events.root -n 100000 -seed 42 -z-frac 0.3
+//	$> gen-opendata -o uncompressed.root -n 1000 -compress=false
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/go-hep/examples/groot/bench-opendata/nanogen"
+	"go-hep.org/x/hep/groot/riofs"
+)
+
+func main() {
+	log.SetPrefix("gen-opendata: ")
+	log.SetFlags(0)
+
+	cfg := nanogen.Default()
+
+	var (
+		oname = flag.String("o", "opendata.root", "path to the output ROOT file")
+		nevts = flag.Int64("n", 100000, "number of events to generate")
+		zip   = flag.Bool("compress", true, "enable compression of the output file (files whose baskets can not be compressed are written without compression)")
+	)
+
+	flag.Uint64Var(&cfg.Seed, "seed", cfg.Seed, "seed of the random number generator")
+	flag.Float64Var(&cfg.Muons, "muons", cfg.Muons, "mean number of additional muons per event")
+	flag.Float64Var(&cfg.Electrons, "electrons", cfg.Electrons, "mean number of additional electrons per event")
+	flag.Float64Var(&cfg.Jets, "jets", cfg.Jets, "mean number of additional jets per event")
+	flag.Float64Var(&cfg.SumEt, "sumet", cfg.SumEt, "mean scalar sum of the transverse energy of the unobserved activity (GeV)")
+
+	flag.Float64Var(&cfg.Z.Frac, "z-frac", cfg.Z.Frac, "fraction of events with a Z->ll decay")
+	flag.Float64Var(&cfg.Z.Mass, "z-mass", cfg.Z.Mass, "mass of the Z peak (GeV)")
+	flag.Float64Var(&cfg.Z.Width, "z-width", cfg.Z.Width, "width of the Z peak (GeV)")
+	flag.Float64Var(&cfg.Z.Pt, "z-pt", cfg.Z.Pt, "mean pT of the Z bosons (GeV)")
+
+	flag.Float64Var(&cfg.Top.Frac, "top-frac", cfg.Top.Frac, "fraction of events with a top-like trijet")
+	flag.Float64Var(&cfg.Top.Mass, "top-mass", cfg.Top.Mass, "mass of the trijet peak (GeV)")
+	flag.Float64Var(&cfg.Top.Width, "top-width", cfg.Top.Width, "width of the trijet peak (GeV)")
+	flag.Float64Var(&cfg.Top.Pt, "top-pt", cfg.Top.Pt, "mean pT of the top quarks (GeV)")
+
+	flag.Float64Var(&cfg.BTag.Frac, "b-frac", cfg.BTag.Frac, "fraction of additional jets originating from a b quark")
+	flag.Float64Var(&cfg.BTag.Light[0], "btag-light-a", cfg.BTag.Light[0], "alpha parameter of the b-tag Beta distribution of light jets")
+	flag.Float64Var(&cfg.BTag.Light[1], "btag-light-b", cfg.BTag.Light[1], "beta parameter of the b-tag Beta distribution of light jets")
+	flag.Float64Var(&cfg.BTag.Heavy[0], "btag-b-a", cfg.BTag.Heavy[0], "alpha parameter of the b-tag Beta distribution of b jets")
+	flag.Float64Var(&cfg.BTag.Heavy[1], "btag-b-b", cfg.BTag.Heavy[1], "beta parameter of the b-tag Beta distribution of b jets")
+
+	flag.Parse()
+
+	var opts []riofs.FileOption
+	if !*zip {
+		opts = append(opts, riofs.WithoutCompression())
+	}
+
+	start := time.Now()
+	err := nanogen.Generate(*oname, *nevts, cfg, opts...)
+	if err != nil {
+		log.Fatalf("could not generate events: %+v", err)
+	}
+
+	log.Printf("generated %d events into %q (delta=%v)", *nevts, *oname, time.Since(start))
+}
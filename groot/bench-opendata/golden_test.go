@@ -0,0 +1,122 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hbook/yodacnv"
+)
+
+var update = flag.Bool("update", false, "update the golden reference histograms")
+
+const (
+	goldenInput  = "testdata/events.root" // small generated NanoAOD-like input file
+	goldenEvents = 1000                   // number of events of the golden input file
+)
+
+// TestGolden runs each registered analysis on a small generated input file
+// and compares the filled histograms with the reference ones, stored in
+// testdata/golden.
+// The references are regenerated with:
+//
+//	$> go test -run Golden -update
+func TestGolden(t *testing.T) {
+	if _, err := os.Stat(goldenInput); os.IsNotExist(err) && *update {
+		err = createTestFile(goldenInput, goldenEvents)
+		if err != nil {
+			t.Fatalf("could not create golden input file: %+v", err)
+		}
+	}
+
+	for _, name := range sortedBenchNames() {
+		bench := benchIDs[name]
+		t.Run(name, func(t *testing.T) {
+			got, err := runHists(bench, config{fname: goldenInput})
+			if err != nil {
+				t.Fatalf("could not run %q: %+v", name, err)
+			}
+
+			fname := filepath.Join("testdata", "golden", name+".yoda")
+			if *update {
+				err = writeYODA(fname, got)
+				if err != nil {
+					t.Fatalf("could not update reference histograms: %+v", err)
+				}
+				return
+			}
+
+			want, err := readYODA(fname)
+			if err != nil {
+				t.Fatalf("could not read reference histograms: %+v", err)
+			}
+
+			// YODA files store values with 7 significant digits.
+			err = cmpHists(got, want, 1e-6)
+			if err != nil {
+				t.Fatalf("histograms differ from reference: %+v", err)
+			}
+		})
+	}
+}
+
+// runHists runs the analysis over the input files and returns
+// its filled histograms.
+func runHists(bench *Bench, cfg config) ([]hbook.Histogram, error) {
+	if _, ok := bench.New().(TreeAnalysis); ok {
+		return runTreeAnalysis(bench, cfg.fname, cfg.rctx)
+	}
+
+	tasks, err := runTasks(context.Background(), []*Bench{bench}, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return tasks[0].hs, tasks[0].err
+}
+
+func writeYODA(fname string, hs []hbook.Histogram) error {
+	vs := make([]yodacnv.Marshaler, len(hs))
+	for i, h := range hs {
+		vs[i] = h.(yodacnv.Marshaler)
+	}
+
+	buf := new(bytes.Buffer)
+	err := yodacnv.Write(buf, vs...)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, buf.Bytes(), 0644)
+}
+
+func readYODA(fname string) ([]hbook.Histogram, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	objs, err := yodacnv.Read(f)
+	if err != nil {
+		return nil, err
+	}
+
+	hs := make([]hbook.Histogram, len(objs))
+	for i, obj := range objs {
+		h, ok := obj.(hbook.Histogram)
+		if !ok {
+			return nil, fmt.Errorf("%s: object %d is not a histogram (%T)", fname, i, obj)
+		}
+		hs[i] = h
+	}
+	return hs, nil
+}
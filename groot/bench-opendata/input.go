@@ -0,0 +1,256 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// inputFile describes an input file.
+type inputFile struct {
+	name    string
+	entries int64 // number of entries of the Events tree
+}
+
+// parseInput returns the list of input files described by spec, a
+// comma-separated list of:
+//   - local or XRootD file names,
+//   - glob patterns of local file names (e.g. "data/*.root"),
+//   - names of text files (with a ".txt" extension), listing one file name
+//     or glob pattern per line. Empty lines and lines starting with '#' are
+//     ignored.
+func parseInput(spec string) ([]string, error) {
+	var fnames []string
+	for _, v := range strings.Split(spec, ",") {
+		v = strings.TrimSpace(v)
+		switch {
+		case v == "":
+			continue
+		case strings.HasSuffix(v, ".txt"):
+			vs, err := readInputList(v)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range vs {
+				names, err := glob(v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid input list %q: %w", v, err)
+				}
+				fnames = append(fnames, names...)
+			}
+		default:
+			names, err := glob(v)
+			if err != nil {
+				return nil, err
+			}
+			fnames = append(fnames, names...)
+		}
+	}
+
+	if len(fnames) == 0 {
+		return nil, fmt.Errorf("no input file in %q", spec)
+	}
+
+	return fnames, nil
+}
+
+// readInputList returns the file names listed in the named text file.
+func readInputList(fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("could not open input list: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		names []string
+		sc    = bufio.NewScanner(f)
+	)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+
+	err = sc.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not read input list %q: %w", fname, err)
+	}
+
+	return names, nil
+}
+
+// glob returns the local files matching the provided pattern,
+// or the pattern itself if it is not a local glob pattern.
+func glob(pattern string) ([]string, error) {
+	if urlScheme(pattern) != "file" || !strings.ContainsAny(pattern, "*?[") {
+		return []string{pattern}, nil
+	}
+
+	names, err := filepath.Glob(strings.TrimPrefix(pattern, "file://"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no file matching %q", pattern)
+	}
+
+	return names, nil
+}
+
+// chain is the chain of the Events trees of a list of input files.
+// Entries of the chain are numbered continuously across files.
+//
+// Trees are processed one after the other, with their own scanner:
+// rtree.Chain scanners do not bind the leaf-count branches of the
+// next tree, unless they were explicitly requested.
+type chain struct {
+	names []string // names of the input files
+	files []*riofs.File
+	io    []*fileIO    // I/O accounting of each file, nil unless enabled
+	trees []rtree.Tree // Events tree of each file
+	offs  []int64      // index in the chain of the first entry of each tree
+}
+
+// openTree opens the input files described by spec (see parseInput) and
+// retrieves the chain of their Events trees.
+// The Events trees must all have the same branches.
+func openTree(spec string) (*chain, error) {
+	fnames, err := parseInput(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := &chain{
+		names: fnames,
+		files: make([]*riofs.File, 0, len(fnames)),
+		io:    make([]*fileIO, 0, len(fnames)),
+		trees: make([]rtree.Tree, 0, len(fnames)),
+		offs:  make([]int64, 0, len(fnames)),
+	}
+
+	off := int64(0)
+	for i, fname := range fnames {
+		f, fio, err := openFile(fname)
+		if err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("could not open ROOT file %q: %w", fname, err)
+		}
+		ch.files = append(ch.files, f)
+		ch.io = append(ch.io, fio)
+
+		o, err := f.Get("Events")
+		if err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("could not retrieve tree from %q: %w", fname, err)
+		}
+
+		tree, ok := o.(rtree.Tree)
+		if !ok {
+			ch.Close()
+			return nil, fmt.Errorf("object Events in %q is not a tree (%T)", fname, o)
+		}
+
+		if i > 0 {
+			err = cmpSchema(ch.trees[0], tree)
+			if err != nil {
+				ch.Close()
+				return nil, fmt.Errorf("schema mismatch between %q and %q: %w", fnames[0], fname, err)
+			}
+		}
+
+		ch.trees = append(ch.trees, tree)
+		ch.offs = append(ch.offs, off)
+		off += tree.Entries()
+	}
+
+	return ch, nil
+}
+
+// entries returns the number of entries of the chain.
+func (ch *chain) entries() int64 {
+	n := int64(0)
+	for _, tree := range ch.trees {
+		n += tree.Entries()
+	}
+	return n
+}
+
+// inputs returns the description of the input files of the chain.
+func (ch *chain) inputs() []inputFile {
+	files := make([]inputFile, len(ch.files))
+	for i, tree := range ch.trees {
+		files[i] = inputFile{
+			name:    ch.names[i],
+			entries: tree.Entries(),
+		}
+	}
+	return files
+}
+
+// Close closes all the input files of the chain, and records their I/O
+// accounting.
+func (ch *chain) Close() error {
+	var err error
+	for i, f := range ch.files {
+		ch.io[i].close()
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// cmpSchema checks that the tree has the same branches and leaves,
+// with the same types, as the reference tree.
+func cmpSchema(ref, tree rtree.Tree) error {
+	for _, rb := range ref.Branches() {
+		b := tree.Branch(rb.Name())
+		if b == nil {
+			return fmt.Errorf("missing branch %q", rb.Name())
+		}
+
+		var (
+			rleaves = rb.Leaves()
+			leaves  = b.Leaves()
+		)
+		if len(leaves) != len(rleaves) {
+			return fmt.Errorf(
+				"branch %q: invalid number of leaves: got=%d, want=%d",
+				rb.Name(), len(leaves), len(rleaves),
+			)
+		}
+		for i, rleaf := range rleaves {
+			leaf := leaves[i]
+			if leaf.Name() != rleaf.Name() || leaf.TypeName() != rleaf.TypeName() {
+				return fmt.Errorf(
+					"branch %q: invalid leaf: got=%s (%s), want=%s (%s)",
+					rb.Name(), leaf.Name(), leaf.TypeName(), rleaf.Name(), rleaf.TypeName(),
+				)
+			}
+		}
+	}
+
+	if got, want := len(tree.Branches()), len(ref.Branches()); got != want {
+		for _, b := range tree.Branches() {
+			if ref.Branch(b.Name()) == nil {
+				return fmt.Errorf("unexpected branch %q", b.Name())
+			}
+		}
+		return fmt.Errorf("invalid number of branches: got=%d, want=%d", got, want)
+	}
+
+	return nil
+}
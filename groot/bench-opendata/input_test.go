@@ -0,0 +1,226 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-hep/examples/groot/bench-opendata/nanogen"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+)
+
+func TestParseInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		f1 = filepath.Join(dir, "f1.root")
+		f2 = filepath.Join(dir, "f2.root")
+		f3 = filepath.Join(dir, "f3.root")
+	)
+	for _, fname := range []string{f1, f2, f3} {
+		err := ioutil.WriteFile(fname, nil, 0644)
+		if err != nil {
+			t.Fatalf("could not create file: %+v", err)
+		}
+	}
+
+	list := filepath.Join(dir, "list.txt")
+	err = ioutil.WriteFile(list, []byte("# input files\n"+f3+"\n\n  "+filepath.Join(dir, "f[12].root")+"\n"), 0644)
+	if err != nil {
+		t.Fatalf("could not create input list: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		spec string
+		want []string
+		err  string
+	}{
+		{spec: f1, want: []string{f1}},
+		{spec: f2 + ", " + f1, want: []string{f2, f1}},
+		{spec: filepath.Join(dir, "*.root"), want: []string{f1, f2, f3}},
+		{spec: list + "," + f1, want: []string{f3, f1, f2, f1}},
+		{
+			spec: "root://eospublic.cern.ch//eos/f*.root",
+			want: []string{"root://eospublic.cern.ch//eos/f*.root"},
+		},
+		{spec: "", err: `no input file in ""`},
+		{spec: filepath.Join(dir, "*.txt.root"), err: "no file matching"},
+		{spec: filepath.Join(dir, "missing.txt"), err: "could not open input list"},
+	} {
+		t.Run("", func(t *testing.T) {
+			got, err := parseInput(tc.spec)
+			switch {
+			case tc.err != "":
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("invalid error: got=%v, want=%q", err, tc.err)
+				}
+				return
+			case err != nil:
+				t.Fatalf("could not parse input %q: %+v", tc.spec, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("invalid input files:\ngot= %q\nwant=%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		fnames  []string
+		entries = []int64{700, 0, 500, 800}
+	)
+	for i, n := range entries {
+		fname := filepath.Join(dir, fmt.Sprintf("f%d.root", i))
+		cfg := nanogen.Default()
+		cfg.Seed += uint64(i)
+		err := nanogen.Generate(fname, n, cfg)
+		if err != nil {
+			t.Fatalf("could not create input file: %+v", err)
+		}
+		fnames = append(fnames, fname)
+	}
+
+	ch, err := openTree(strings.Join(fnames, ","))
+	if err != nil {
+		t.Fatalf("could not open chain: %+v", err)
+	}
+	inputs := ch.inputs()
+	ch.Close()
+
+	for i, f := range inputs {
+		if f.name != fnames[i] || f.entries != entries[i] {
+			t.Fatalf("invalid input file %d: got=%+v", i, f)
+		}
+	}
+
+	for _, name := range sortedBenchNames() {
+		bench := benchIDs[name]
+		for _, ctx := range []RunContext{
+			{},
+			{Start: 250, N: 400, Stride: 3},
+			{Start: 690, N: 12},
+		} {
+			ctx := ctx
+			t.Run(name+"-"+ctxName(ctx), func(t *testing.T) {
+				got, err := runHists(bench, config{fname: strings.Join(fnames, ","), rctx: ctx})
+				if err != nil {
+					t.Fatalf("could not run %q over chain: %+v", name, err)
+				}
+
+				var (
+					want []hbook.Histogram
+					off  int64
+				)
+				for i, fname := range fnames {
+					n := entries[i]
+					local := ctx.local(off, n)
+					off += n
+					if local.Entries(n) == 0 {
+						continue
+					}
+					hs, err := runHists(bench, config{fname: fname, rctx: local})
+					if err != nil {
+						t.Fatalf("could not run %q over %q: %+v", name, fname, err)
+					}
+					if want == nil {
+						want = hs
+						continue
+					}
+					err = mergeHists(want, hs)
+					if err != nil {
+						t.Fatalf("could not merge histograms: %+v", err)
+					}
+				}
+
+				err = cmpHists(got, want, 1e-9)
+				if err != nil {
+					t.Fatalf("chain and per-file histograms differ: %+v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestChainSchemaMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.root")
+	err = createTestFile(good, 10)
+	if err != nil {
+		t.Fatalf("could not create input file: %+v", err)
+	}
+
+	bad := filepath.Join(dir, "bad.root")
+	err = createTree(bad, []rtree.WriteVar{{Name: "MET_pt", Value: new(float64)}})
+	if err != nil {
+		t.Fatalf("could not create input file: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		spec string
+		want string
+	}{
+		{spec: good + "," + bad, want: `missing branch "nMuon"`},
+		{spec: bad + "," + good, want: `branch "MET_pt": invalid leaf: got=MET_pt (float32), want=MET_pt (float64)`},
+	} {
+		_, err := openTree(tc.spec)
+		if err == nil {
+			t.Fatalf("%s: expected an error", tc.spec)
+		}
+		if !strings.Contains(err.Error(), "schema mismatch") || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: invalid error: %v", tc.spec, err)
+		}
+	}
+}
+
+// createTree creates a ROOT file with an Events tree holding 1 entry
+// of the provided variables.
+func createTree(fname string, vars []rtree.WriteVar) error {
+	f, err := groot.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := rtree.NewWriter(f, "Events", vars)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write()
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
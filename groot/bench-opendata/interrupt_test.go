@@ -0,0 +1,190 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rhist"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/hbook"
+)
+
+// cancelAnalysis is an analysis canceling the run after n events.
+type cancelAnalysis struct {
+	Analysis
+	n      int64
+	cancel context.CancelFunc
+}
+
+func (ana *cancelAnalysis) Process() error {
+	err := ana.Analysis.Process()
+	ana.n--
+	if ana.n == 0 {
+		ana.cancel()
+	}
+	return err
+}
+
+// cancelFrame is a frame analysis canceling the run after its first frame.
+type cancelFrame struct {
+	frameAnalysis
+	cancel context.CancelFunc
+}
+
+func (ana *cancelFrame) ProcessFrame(f *frame) error {
+	defer ana.cancel()
+	return ana.frameAnalysis.ProcessFrame(f)
+}
+
+func TestInterrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "events.root")
+	err = createTestFile(fname, 2000)
+	if err != nil {
+		t.Fatalf("could not create input file: %+v", err)
+	}
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		nworkers int
+		bench    func(cancel context.CancelFunc) *Bench
+		min, max int64 // expected range of processed entries
+	}{
+		{
+			name:     "basic",
+			nworkers: 1,
+			bench: func(cancel context.CancelFunc) *Bench {
+				return &Bench{Name: "01-basic", New: func() Analysis {
+					return &cancelAnalysis{Analysis: benchIDs["01-basic"].New(), n: 100, cancel: cancel}
+				}}
+			},
+			min: 100, max: 100,
+		},
+		{
+			name:     "parallel",
+			nworkers: 3,
+			bench: func(cancel context.CancelFunc) *Bench {
+				return &Bench{Name: "05-basic", New: func() Analysis {
+					return &cancelAnalysis{Analysis: benchIDs["05-basic"].New(), n: 100, cancel: cancel}
+				}}
+			},
+			min: 100, max: 300,
+		},
+		{
+			name:     "arrow",
+			nworkers: 1,
+			bench: func(cancel context.CancelFunc) *Bench {
+				return &Bench{Name: "04-arrow", New: func() Analysis {
+					ana := newArrow(&cancelFrame{frameAnalysis: &arrow4{}, cancel: cancel})
+					ana.chunk = 300
+					return ana
+				}}
+			},
+			min: 300, max: 300,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var (
+				bench = tc.bench(cancel)
+				rep   = new(Report)
+				cfg   = config{fname: fname, nworkers: tc.nworkers, out: out}
+			)
+			beg := newUsage()
+			hs, cf, nevts, err := run(ctx, bench, cfg)
+			end := newUsage()
+			if !interrupted(err) {
+				t.Fatalf("expected an interrupted run, got: %+v", err)
+			}
+			if nevts < tc.min || nevts > tc.max {
+				t.Fatalf("invalid number of processed entries: got=%d, want in [%d, %d]", nevts, tc.min, tc.max)
+			}
+			if got, want := cf.Cuts[0].N, nevts; got != want {
+				t.Fatalf("invalid cut-flow: got=%d events, want=%d", got, want)
+			}
+			if got, want := cf.Cuts[len(cf.Cuts)-1].N, int64(hs[0].Entries()); got != want {
+				t.Fatalf("invalid histogram: got=%d entries, want=%d", got, want)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, bench.Name+".partial.png")); err != nil {
+				t.Fatalf("partial results were not saved: %+v", err)
+			}
+			for _, h := range hs {
+				ann := h.Annotation()
+				if ann["partial"] != true || ann["entries"] != nevts {
+					t.Fatalf("histogram %q not marked as partial: %v", h.Name(), ann)
+				}
+				if title, want := ann["title"].(string), fmt.Sprintf("[partial: %d entries]", nevts); !strings.HasSuffix(title, want) {
+					t.Fatalf("invalid title of partial histogram %q: got=%q, want suffix %q", h.Name(), title, want)
+				}
+			}
+
+			rep.add(bench, cfg, nevts, hs, cf, beg, end, err)
+			if ana := rep.Analyses[0]; !ana.Partial || ana.Error != "" || ana.Events != nevts {
+				t.Fatalf("invalid report of a partial run: %+v", ana)
+			}
+			if got := rep.summaries(); len(got) != 0 {
+				t.Fatalf("partial runs should not be summarized: %+v", got)
+			}
+
+			// the mark is saved with the ROOT histograms.
+			oroot := filepath.Join(dir, tc.name+".root")
+			rout := &output{root: oroot, hists: make(map[string][]hbook.Histogram)}
+			rout.add(bench, hs)
+			if err := rout.save(); err != nil {
+				t.Fatalf("could not save histograms: %+v", err)
+			}
+			f, err := groot.Open(oroot)
+			if err != nil {
+				t.Fatalf("could not open ROOT file: %+v", err)
+			}
+			defer f.Close()
+			o, err := riofs.Dir(f).Get(bench.Name + "/" + hs[0].Name())
+			if err != nil {
+				t.Fatalf("could not retrieve histogram: %+v", err)
+			}
+			if got, want := o.(rhist.H1).Title(), hs[0].Annotation()["title"]; got != want {
+				t.Fatalf("invalid title of saved partial histogram: got=%q, want=%q", got, want)
+			}
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		<-ctx.Done()
+
+		for _, name := range []string{"01-basic", "01-rsql", "05-rsql", "06-arrow"} {
+			_, _, nevts, err := run(ctx, benchIDs[name], config{fname: fname, out: out})
+			if err == nil || ctx.Err() != context.DeadlineExceeded || !interrupted(err) {
+				t.Fatalf("%s: expected a deadline error, got: %+v", name, err)
+			}
+			if nevts != 0 {
+				t.Fatalf("%s: invalid number of processed entries: %d", name, nevts)
+			}
+		}
+	})
+}
@@ -0,0 +1,363 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// ioTotal is the I/O accounting of all the closed input files, enabled
+// with -io.
+var ioTotal struct {
+	sync.Mutex
+	enabled bool
+	stats   ioStats
+}
+
+// enableIO enables (or disables) the I/O accounting of the input files
+// opened from now on, and resets the accounting of the closed ones.
+func enableIO(enabled bool) {
+	ioTotal.Lock()
+	defer ioTotal.Unlock()
+	ioTotal.enabled = enabled
+	ioTotal.stats = ioStats{}
+}
+
+// ioStats accounts for the baskets read from input files, and for the
+// time spent scanning their entries.
+type ioStats struct {
+	read        time.Duration // time spent reading the files
+	decompress  time.Duration // time spent loading baskets, besides reading them
+	deserialize time.Duration // time spent loading entries from loaded baskets
+	user        time.Duration // time spent processing entries
+	branches    map[string]branchIO
+}
+
+// branchIO accounts for the baskets read from a branch.
+type branchIO struct {
+	baskets int64 // number of baskets read
+	zbytes  int64 // compressed bytes of the baskets
+	bytes   int64 // uncompressed bytes of the baskets
+}
+
+func (st *ioStats) add(o ioStats) {
+	st.read += o.read
+	st.decompress += o.decompress
+	st.deserialize += o.deserialize
+	st.user += o.user
+	for name, b := range o.branches {
+		st.addBasket(name, b)
+	}
+}
+
+func (st *ioStats) addBasket(branch string, b branchIO) {
+	if st.branches == nil {
+		st.branches = make(map[string]branchIO)
+	}
+	v := st.branches[branch]
+	v.baskets += b.baskets
+	v.zbytes += b.zbytes
+	v.bytes += b.bytes
+	st.branches[branch] = v
+}
+
+// sub returns the accounting of st since the o snapshot.
+func (st ioStats) sub(o ioStats) ioStats {
+	d := ioStats{
+		read:        st.read - o.read,
+		decompress:  st.decompress - o.decompress,
+		deserialize: st.deserialize - o.deserialize,
+		user:        st.user - o.user,
+	}
+	for name, b := range st.branches {
+		ob := o.branches[name]
+		b.baskets -= ob.baskets
+		b.zbytes -= ob.zbytes
+		b.bytes -= ob.bytes
+		if b.baskets != 0 {
+			d.addBasket(name, b)
+		}
+	}
+	return d
+}
+
+// ioSnapshot returns a copy of the I/O accounting of the closed input
+// files, and whether the accounting is enabled.
+func ioSnapshot() (ioStats, bool) {
+	ioTotal.Lock()
+	defer ioTotal.Unlock()
+	var st ioStats
+	st.add(ioTotal.stats)
+	return st, ioTotal.enabled
+}
+
+// fileIO is the I/O accounting of an input file.
+// It is only updated by the goroutine reading the file, and merged into
+// ioTotal when the file is closed.
+type fileIO struct {
+	baskets int64 // number of baskets read
+	stats   ioStats
+}
+
+// newFileIO returns the I/O accounting of a new input file, or nil if
+// the accounting is disabled.
+func newFileIO() *fileIO {
+	ioTotal.Lock()
+	defer ioTotal.Unlock()
+	if !ioTotal.enabled {
+		return nil
+	}
+	return new(fileIO)
+}
+
+// read accounts for the read of p, started at beg.
+//
+// rtree reads each basket with a single read of its whole key, whose
+// header holds the name of its branch and its compressed and uncompressed
+// sizes: these reads are recognized from their content.
+// The compressed payload is then read again while it is decompressed.
+func (fio *fileIO) read(p []byte, beg time.Time) {
+	fio.stats.read += time.Since(beg)
+
+	branch, b, ok := parseBasketKey(p)
+	if !ok {
+		return
+	}
+	fio.baskets++
+	fio.stats.addBasket(branch, b)
+}
+
+// close merges the accounting of the file into ioTotal.
+func (fio *fileIO) close() {
+	if fio == nil {
+		return
+	}
+	ioTotal.Lock()
+	defer ioTotal.Unlock()
+	ioTotal.stats.add(fio.stats)
+	fio.stats = ioStats{}
+}
+
+// ioMark is the state of the accounting of a file before loading an entry.
+type ioMark struct {
+	beg     time.Time
+	baskets int64
+	read    time.Duration
+}
+
+// mark returns the state of the accounting of the file before loading an
+// entry.
+func (fio *fileIO) mark() ioMark {
+	if fio == nil {
+		return ioMark{}
+	}
+	return ioMark{beg: time.Now(), baskets: fio.baskets, read: fio.stats.read}
+}
+
+// loaded accounts for the time spent loading an entry since m, besides
+// reading the file: as decompression if baskets were read in the
+// meantime (it then also includes the deserialization of that entry),
+// as deserialization otherwise.
+func (fio *fileIO) loaded(m ioMark) {
+	if fio == nil {
+		return
+	}
+	d := time.Since(m.beg) - (fio.stats.read - m.read)
+	switch {
+	case fio.baskets != m.baskets:
+		fio.stats.decompress += d
+	default:
+		fio.stats.deserialize += d
+	}
+}
+
+// processed accounts for d spent processing an entry.
+func (fio *fileIO) processed(d time.Duration) {
+	if fio == nil {
+		return
+	}
+	fio.stats.user += d
+}
+
+// parseBasketKey parses the header of the TKey of a TBasket, of the branch
+// named by the key, from a buffer holding the whole key.
+func parseBasketKey(p []byte) (string, branchIO, bool) {
+	const hdr = 18 // nbytes, version, objlen, datime, keylen and cycle
+	if len(p) < hdr {
+		return "", branchIO{}, false
+	}
+	var (
+		nbytes = int32(binary.BigEndian.Uint32(p[0:]))
+		vers   = int16(binary.BigEndian.Uint16(p[4:]))
+		objlen = int32(binary.BigEndian.Uint32(p[6:]))
+		keylen = int32(binary.BigEndian.Uint16(p[14:]))
+	)
+	if int(nbytes) != len(p) || keylen < hdr || keylen > nbytes || objlen < 0 {
+		return "", branchIO{}, false
+	}
+
+	// seekkey and seekpdir, with 64b offsets for big files.
+	pos := hdr + 8
+	if vers > 1000 {
+		pos = hdr + 16
+	}
+	var strs [2]string // class and name
+	for i := range strs {
+		if pos >= int(keylen) {
+			return "", branchIO{}, false
+		}
+		n := int(p[pos])
+		pos++
+		if n == 255 {
+			if pos+4 > int(keylen) {
+				return "", branchIO{}, false
+			}
+			n = int(binary.BigEndian.Uint32(p[pos:]))
+			pos += 4
+		}
+		if n < 0 || pos+n > int(keylen) {
+			return "", branchIO{}, false
+		}
+		strs[i] = string(p[pos : pos+n])
+		pos += n
+	}
+	if strs[0] != "TBasket" {
+		return "", branchIO{}, false
+	}
+
+	return strs[1], branchIO{
+		baskets: 1,
+		zbytes:  int64(nbytes - keylen),
+		bytes:   int64(objlen),
+	}, true
+}
+
+// IOReport describes the reads of the input files during a run, recorded
+// with -io: the baskets read from each branch, and the split of the time
+// spent scanning the Events tree between reading the input files,
+// decompressing baskets, deserializing entries and processing them.
+//
+// Times are summed over the workers of -j.
+// Decompression and deserialization are only measured for the analyses
+// scanning the Events tree entry by entry (basic and struct): the other
+// ones only report the time spent reading the input files.
+type IOReport struct {
+	ReadTime          float64    `json:"read_time_s"`
+	DecompressTime    float64    `json:"decompress_time_s"`
+	DeserializeTime   float64    `json:"deserialize_time_s"`
+	UserTime          float64    `json:"user_time_s"`
+	Baskets           int64      `json:"baskets"`
+	CompressedBytes   int64      `json:"compressed_bytes"`
+	UncompressedBytes int64      `json:"uncompressed_bytes"`
+	Branches          []BranchIO `json:"branches"`
+}
+
+// BranchIO describes the baskets read from a branch.
+type BranchIO struct {
+	Name              string `json:"name"`
+	Baskets           int64  `json:"baskets"`
+	CompressedBytes   int64  `json:"compressed_bytes"`
+	UncompressedBytes int64  `json:"uncompressed_bytes"`
+}
+
+// newIOReport returns the report of the reads of the input files between
+// the beg and end snapshots of the resources used by the process, or nil
+// if the I/O accounting is disabled.
+func newIOReport(beg, end usage) *IOReport {
+	if !end.ioEnabled {
+		return nil
+	}
+
+	st := end.io.sub(beg.io)
+	rep := &IOReport{
+		ReadTime:        st.read.Seconds(),
+		DecompressTime:  st.decompress.Seconds(),
+		DeserializeTime: st.deserialize.Seconds(),
+		UserTime:        st.user.Seconds(),
+		Branches:        make([]BranchIO, 0, len(st.branches)),
+	}
+	for name, b := range st.branches {
+		rep.Baskets += b.baskets
+		rep.CompressedBytes += b.zbytes
+		rep.UncompressedBytes += b.bytes
+		rep.Branches = append(rep.Branches, BranchIO{
+			Name:              name,
+			Baskets:           b.baskets,
+			CompressedBytes:   b.zbytes,
+			UncompressedBytes: b.bytes,
+		})
+	}
+	sort.Slice(rep.Branches, func(i, j int) bool {
+		return rep.Branches[i].Name < rep.Branches[j].Name
+	})
+
+	return rep
+}
+
+// printIO prints the report of the reads of the input files between the
+// beg and end snapshots of the resources used by the process, if the I/O
+// accounting is enabled.
+func printIO(beg, end usage) {
+	rep := newIOReport(beg, end)
+	if rep == nil {
+		return
+	}
+	err := rep.writeTable(os.Stdout)
+	if err != nil {
+		log.Printf("could not print I/O report: %v", err)
+	}
+}
+
+// writeTable writes the time split and the baskets read from each branch
+// as a table to w.
+func (rep *IOReport) writeTable(w io.Writer) error {
+	var (
+		times = []float64{rep.ReadTime, rep.DecompressTime, rep.DeserializeTime, rep.UserTime}
+		total = 0.0
+	)
+	for _, v := range times {
+		total += v
+	}
+	fmt.Fprintf(w, "I/O:")
+	for i, name := range []string{"read", "decompress", "deserialize", "user"} {
+		frac := 0.0
+		if total > 0 {
+			frac = times[i] / total
+		}
+		fmt.Fprintf(w, " %s=%.3fs (%.1f%%)", name, times[i], 100*frac)
+	}
+	fmt.Fprintf(w, "\n")
+
+	ratio := func(zbytes, bytes int64) float64 {
+		if zbytes == 0 {
+			return 0
+		}
+		return float64(bytes) / float64(zbytes)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "branch\tbaskets\tcompressed\tuncompressed\tratio\n")
+	for _, b := range rep.Branches {
+		fmt.Fprintf(
+			tw, "%s\t%d\t%d\t%d\t%.2f\n",
+			b.Name, b.Baskets, b.CompressedBytes, b.UncompressedBytes,
+			ratio(b.CompressedBytes, b.UncompressedBytes),
+		)
+	}
+	fmt.Fprintf(
+		tw, "total\t%d\t%d\t%d\t%.2f\n",
+		rep.Baskets, rep.CompressedBytes, rep.UncompressedBytes,
+		ratio(rep.CompressedBytes, rep.UncompressedBytes),
+	)
+	return tw.Flush()
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestIOReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "events.root")
+	err = createTestFile(fname, 20000)
+	if err != nil {
+		t.Fatalf("could not create input file: %+v", err)
+	}
+
+	ch, err := openTree(fname)
+	if err != nil {
+		t.Fatalf("could not open input file: %+v", err)
+	}
+	tree := ch.trees[0]
+	ch.Close()
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	enableIO(true)
+	defer enableIO(false)
+
+	for _, tc := range []struct {
+		name     string
+		nworkers int
+	}{
+		{name: "05-basic", nworkers: 1},
+		{name: "05-basic", nworkers: 3},
+		{name: "05-struct", nworkers: 1},
+		{name: "05-arrow", nworkers: 1},
+	} {
+		t.Run(fmt.Sprintf("%s-j=%d", tc.name, tc.nworkers), func(t *testing.T) {
+			var (
+				bench = benchIDs[tc.name]
+				cfg   = config{fname: fname, nworkers: tc.nworkers, out: out}
+			)
+			beg := newUsage()
+			_, _, _, err := run(context.Background(), bench, cfg)
+			if err != nil {
+				t.Fatalf("could not run %q: %+v", tc.name, err)
+			}
+			end := newUsage()
+
+			rep := newIOReport(beg, end)
+			if rep == nil {
+				t.Fatalf("no I/O report")
+			}
+
+			// scanned branches, with their leaf-count branches: the arrow
+			// analyses also only read the branches they need.
+			var (
+				want []string
+				set  = make(map[string]bool)
+			)
+			for _, v := range bench.New().Vars() {
+				set[v.Name] = true
+				if lc := tree.Branch(v.Name).Leaves()[0].LeafCount(); lc != nil {
+					set[lc.Name()] = true
+				}
+			}
+			for name := range set {
+				want = append(want, name)
+			}
+			sort.Strings(want)
+
+			var got []string
+			for _, b := range rep.Branches {
+				got = append(got, b.Name)
+				if b.Baskets <= 0 || b.CompressedBytes <= 0 || b.UncompressedBytes <= 0 {
+					t.Fatalf("invalid accounting for branch %q: %+v", b.Name, b)
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("invalid branches:\ngot= %q\nwant=%q", got, want)
+			}
+
+			if rep.Baskets <= int64(len(rep.Branches)) {
+				t.Fatalf("expected more than one basket per branch, got %d baskets", rep.Baskets)
+			}
+			if rep.CompressedBytes > end.bytes-beg.bytes {
+				t.Fatalf("more compressed bytes (%d) than bytes read (%d)", rep.CompressedBytes, end.bytes-beg.bytes)
+			}
+			if rep.ReadTime <= 0 {
+				t.Fatalf("invalid read time: %v", rep.ReadTime)
+			}
+			if _, ok := bench.New().(TreeAnalysis); !ok {
+				if rep.DecompressTime <= 0 || rep.DeserializeTime <= 0 || rep.UserTime <= 0 {
+					t.Fatalf("invalid time split: %+v", rep)
+				}
+			}
+
+			o := new(strings.Builder)
+			err = rep.writeTable(o)
+			if err != nil {
+				t.Fatalf("could not write I/O report: %+v", err)
+			}
+			if !strings.Contains(o.String(), "\nMuon_pt ") {
+				t.Fatalf("missing Muon_pt branch:\n%s", o.String())
+			}
+		})
+	}
+
+	enableIO(false)
+	beg := newUsage()
+	_, _, _, err = run(context.Background(), benchIDs["05-basic"], config{fname: fname, out: out})
+	if err != nil {
+		t.Fatalf("could not run: %+v", err)
+	}
+	if rep := newIOReport(beg, newUsage()); rep != nil {
+		t.Fatalf("unexpected I/O report: %+v", rep)
+	}
+}
+
+func TestParseBasketKey(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		p    []byte
+	}{
+		{name: "empty"},
+		{name: "short", p: make([]byte, 10)},
+		{name: "zeros", p: make([]byte, 100)},
+		{
+			name: "not-a-basket",
+			p:    basketKey("TTree", "Events", 100),
+		},
+		{
+			name: "truncated",
+			p:    basketKey("TBasket", "Muon_pt", 100)[:80],
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if name, _, ok := parseBasketKey(tc.p); ok {
+				t.Fatalf("unexpected basket of branch %q", name)
+			}
+		})
+	}
+
+	p := basketKey("TBasket", "Muon_pt", 100)
+	name, b, ok := parseBasketKey(p)
+	if !ok {
+		t.Fatalf("could not parse basket key")
+	}
+	want := branchIO{baskets: 1, zbytes: 100, bytes: 1000}
+	if name != "Muon_pt" || b != want {
+		t.Fatalf("invalid basket: got=%q %+v, want=%q %+v", name, b, "Muon_pt", want)
+	}
+}
+
+// basketKey returns a small-file key of the provided class and name, with
+// a payload of n bytes decompressing to 1000 bytes.
+func basketKey(class, name string, n int) []byte {
+	p := []byte{
+		0, 0, 0, 0, // nbytes
+		0, 4, // version
+		0, 0, 3, 232, // objlen
+		0, 0, 0, 0, // datime
+		0, 0, // keylen
+		0, 1, // cycle
+		0, 0, 0, 100, // seekkey
+		0, 0, 0, 100, // seekpdir
+	}
+	for _, s := range []string{class, name, "Events"} {
+		p = append(p, byte(len(s)))
+		p = append(p, s...)
+	}
+	keylen := len(p)
+	p = append(p, make([]byte, n)...)
+	nbytes := len(p)
+	p[0], p[1], p[2], p[3] = byte(nbytes>>24), byte(nbytes>>16), byte(nbytes>>8), byte(nbytes)
+	p[14], p[15] = byte(keylen>>8), byte(keylen)
+	return p
+}
@@ -5,39 +5,52 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"sort"
 	"strings"
-	"time"
+	"sync/atomic"
 
+	"github.com/go-hep/examples/groot/progress"
 	"github.com/pkg/profile"
-
 	_ "go-hep.org/x/hep/groot/riofs/plugin/xrootd"
+	"go-hep.org/x/hep/hbook"
 )
 
 var (
-	// IDs of OpenData benchmarks
-	benchIDs = map[string]func(name string) error{
-		"01-basic": basic1,
-		"02-basic": basic2,
-		"03-basic": basic3,
-		"04-basic": basic4,
-		"05-basic": basic5,
-		"06-basic": basic6,
-		"07-basic": basic7,
-		"08-basic": basic8,
-		"01-rsql":  rsql1,
-	}
+	// IDs of OpenData benchmarks.
+	// Benchmarks register themselves from their own file.
+	benchIDs   = make(map[string]*Bench)
 	benchNames []string
 )
 
+// config holds the configuration of a benchmarks run.
+type config struct {
+	fname    string        // input files to analyze (see parseInput)
+	inputs   []inputFile   // input files, with their number of entries
+	nworkers int           // number of concurrent workers
+	rctx     RunContext    // entries of the input files to analyze
+	count    int           // number of measured runs of each benchmark
+	warmup   int           // number of warm-up runs of each benchmark
+	report   *Report       // report of the measured runs, if any
+	out      *output       // output plots and histograms
+	ckpt     *checkpointer // checkpoints of the runs, if any
+	skim     *skimFile     // skim of the events passing the selections, if any
+	friend   *friendFile   // friend trees of the quantities derived by the analyses, if any
+	progress bool          // whether to report the progress of the runs
+}
+
 func main() {
 	log.SetPrefix("bench-opendata: ")
 	log.SetFlags(0)
 
+	benchNames = sortedBenchNames()
+
 	var (
 		benchFlag = flag.String(
 			"bench", "",
@@ -45,16 +58,40 @@ func main() {
 		)
 		fnameFlag = flag.String(
 			"f", "root://eospublic.cern.ch//eos/root-eos/benchmark/Run2012B_SingleMu.root",
-			"input file to analyze",
+			"input files to analyze: comma-separated list of files, glob patterns or .txt files listing them",
 		)
-		listFlag = flag.Bool("list", false, "list all available benchmarks and exits")
-		profFlag = flag.Bool("profile", false, "enable/disable CPU profiling")
+		listFlag  = flag.Bool("list", false, "list all available benchmarks and exits")
+		sharFlag  = flag.Bool("shared", false, "run all benchmarks during a single, shared, scan of the input file")
+		nprocFlag = flag.Int("j", 1, "number of concurrent workers processing the input file")
+		profFlag  = flag.Bool("profile", false, "enable/disable CPU profiling")
+		repFlag   = flag.String("report", "", "path to a JSON (or CSV, with a .csv extension) report of the run")
+		countFlag = flag.Int("count", 1, "number of measured runs of each benchmark")
+		warmFlag  = flag.Int("warmup", 0, "number of warm-up runs of each benchmark, before the measured ones")
+		bfmtFlag  = flag.String("benchfmt", "", "path to a file where to write the measured runs in the Go benchmark format")
+		startFlag = flag.Int64("start", 0, "index of the first entry to process")
+		nevtsFlag = flag.Int64("n", 0, "maximum number of entries to process (0: all entries)")
+		strdFlag  = flag.Int64("stride", 1, "process one entry every stride entries")
+		odirFlag  = flag.String("o", ".", "directory where to save the plots")
+		fmtFlag   = flag.String("format", "png", "comma-separated list of image formats of the plots (eps,jpg,pdf,png,svg,tex,tif)")
+		rootFlag  = flag.String("root", "", "path to a ROOT file where to save the filled histograms, under a directory per benchmark")
+		tmoFlag   = flag.Duration("timeout", 0, "maximum duration of the run, after which partial results are saved (0: no timeout)")
+		ckptFlag  = flag.String("checkpoint", "", "directory where to periodically save the state of the analyses, to resume them with -resume")
+		ckptNFlag = flag.Int64("checkpoint-every", defaultCheckpointEvery, "number of entries processed between two checkpoints")
+		resFlag   = flag.Bool("resume", false, "resume the analyses from their last checkpoint in the -checkpoint directory")
+		progFlag  = flag.Bool("progress", true, "report the progress of the runs on stderr")
+		skimFlag  = flag.String("skim", "", "path to a ROOT file where to write the events passing the selection of each analysis, under a directory per benchmark")
+		skimBFlag = flag.String("skim-branches", "", "comma-separated list of branches (or glob patterns) to write to the -skim file (default: the branches read by each analysis)")
+		frndFlag  = flag.String("friend", "", "path to a ROOT file where to write the quantities derived by each analysis from each event, as a friend tree of the Events trees under a directory per benchmark")
+		ioFlag    = flag.Bool("io", false, "account for the baskets read from each branch, and split the scan time between I/O, decompression, deserialization and analysis code")
 	)
 
 	flag.Parse()
 
 	if *listFlag {
-		log.Printf("available OpenData benchmark examples: %q", benchNames)
+		log.Printf("available OpenData benchmark examples:")
+		for _, name := range benchNames {
+			log.Printf("  %-10s %s", name, benchIDs[name].Doc)
+		}
 		os.Exit(0)
 	}
 
@@ -76,32 +113,252 @@ func main() {
 
 	log.Printf("running benchs: %q", benchs)
 
+	if *countFlag < 1 {
+		log.Fatalf("invalid number of runs: %d", *countFlag)
+	}
+	if *warmFlag < 0 {
+		log.Fatalf("invalid number of warm-up runs: %d", *warmFlag)
+	}
+	if *startFlag < 0 {
+		log.Fatalf("invalid first entry: %d", *startFlag)
+	}
+	if *nevtsFlag < 0 {
+		log.Fatalf("invalid number of entries: %d", *nevtsFlag)
+	}
+	if *strdFlag < 1 {
+		log.Fatalf("invalid stride: %d", *strdFlag)
+	}
+	if *tmoFlag < 0 {
+		log.Fatalf("invalid timeout: %v", *tmoFlag)
+	}
+	if *ckptNFlag < 1 {
+		log.Fatalf("invalid number of entries between checkpoints: %d", *ckptNFlag)
+	}
+	if *resFlag && *ckptFlag == "" {
+		log.Fatalf("-resume requires a -checkpoint directory")
+	}
+	if *ckptFlag != "" && (*countFlag > 1 || *warmFlag > 0) {
+		log.Fatalf("checkpoints can not be used with repeated runs (-count, -warmup)")
+	}
+
+	if *skimFlag != "" && (*nprocFlag > 1 || *ckptFlag != "" || *countFlag > 1 || *warmFlag > 0) {
+		log.Fatalf("skims can not be used with concurrent workers (-j), checkpoints or repeated runs (-count, -warmup)")
+	}
+	if *frndFlag != "" && (*nprocFlag > 1 || *ckptFlag != "" || *countFlag > 1 || *warmFlag > 0) {
+		log.Fatalf("friend trees can not be written by concurrent workers (-j), checkpoints or repeated runs (-count, -warmup)")
+	}
+	if *frndFlag != "" && (*startFlag > 0 || *nevtsFlag > 0 || *strdFlag > 1) {
+		log.Fatalf("friend trees require processing all the entries (-start, -n, -stride)")
+	}
+
+	out, err := newOutput(*odirFlag, *fmtFlag, *rootFlag)
+	if err != nil {
+		log.Fatalf("invalid output: %+v", err)
+	}
+
+	cfg := config{
+		fname:    *fnameFlag,
+		nworkers: *nprocFlag,
+		count:    *countFlag,
+		warmup:   *warmFlag,
+		rctx: RunContext{
+			Start:  *startFlag,
+			N:      *nevtsFlag,
+			Stride: *strdFlag,
+		},
+		report:   new(Report),
+		out:      out,
+		progress: *progFlag,
+	}
+
+	if *ckptFlag != "" {
+		err := os.MkdirAll(*ckptFlag, 0755)
+		if err != nil {
+			log.Fatalf("could not create checkpoint directory: %+v", err)
+		}
+		cfg.ckpt = &checkpointer{
+			dir:    *ckptFlag,
+			every:  *ckptNFlag,
+			resume: *resFlag,
+		}
+	}
+
+	enableIO(*ioFlag)
+
+	ch, err := openTree(cfg.fname)
+	if err != nil {
+		log.Fatalf("could not open input files: %+v", err)
+	}
+	cfg.inputs = ch.inputs()
+	ch.Close()
+
+	if *skimFlag != "" {
+		cfg.skim, err = newSkimFile(*skimFlag, *skimBFlag)
+		if err != nil {
+			log.Fatalf("could not create skim: %+v", err)
+		}
+	}
+
+	if *frndFlag != "" {
+		cfg.friend, err = newFriendFile(*frndFlag)
+		if err != nil {
+			log.Fatalf("could not create friend trees: %+v", err)
+		}
+	}
+
+	for _, f := range cfg.inputs {
+		log.Printf("input %q: %d entries", f.name, f.entries)
+	}
+
+	ctx, stop := newContext()
+	defer stop()
+	if *tmoFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *tmoFlag)
+		defer cancel()
+	}
+
 	allGood := true
-	for _, name := range benchs {
-		err := runBench(name, *fnameFlag)
+	switch {
+	case *sharFlag:
+		err := repeat(cfg, func(cfg config) error {
+			return runShared(ctx, benchs, cfg)
+		})
+		if err != nil && !interrupted(err) {
+			log.Printf("could not run benchs: %v", err)
+			allGood = false
+		}
+	default:
+		for _, name := range benchs {
+			name := name
+			err := repeat(cfg, func(cfg config) error {
+				return runBench(ctx, name, cfg)
+			})
+			if interrupted(err) {
+				break
+			}
+			if err != nil {
+				log.Printf("could not run bench %q: %v", name, err)
+				allGood = false
+			}
+		}
+	}
+
+	if cfg.count > 1 {
+		err := cfg.report.writeStats(os.Stdout)
+		if err != nil {
+			log.Printf("could not write statistics: %v", err)
+			allGood = false
+		}
+	}
+
+	if *repFlag != "" {
+		err := cfg.report.Save(*repFlag)
+		if err != nil {
+			log.Printf("could not save report: %v", err)
+			allGood = false
+		}
+	}
+
+	err = cfg.out.save()
+	if err != nil {
+		log.Printf("could not save histograms: %v", err)
+		allGood = false
+	}
+
+	err = cfg.skim.Close()
+	if err != nil {
+		log.Printf("could not save skim: %v", err)
+		allGood = false
+	}
+
+	err = cfg.friend.Close()
+	if err != nil {
+		log.Printf("could not save friend trees: %v", err)
+		allGood = false
+	}
+
+	if *bfmtFlag != "" {
+		err := saveBenchFmt(*bfmtFlag, cfg.report)
 		if err != nil {
-			log.Printf("could not run bench %q: %v", name, err)
+			log.Printf("could not save benchmark results: %v", err)
 			allGood = false
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		log.Fatalf("run interrupted (%v): partial results saved", err)
+	}
+
 	if !allGood {
 		log.Fatalf("at least one benchmark failed")
 	}
 }
 
-func runBench(id, fname string) error {
-	fct, ok := benchIDs[id]
+// newContext returns a context canceled on SIGINT.
+// A second SIGINT terminates the process.
+func newContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt)
+	go func() {
+		defer signal.Stop(sigc)
+		select {
+		case <-sigc:
+			log.Printf("interrupted: stopping and saving partial results...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// interrupted returns whether err reports a run stopped on SIGINT or
+// after the -timeout deadline.
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// repeat calls fct cfg.warmup+cfg.count times.
+// Warm-up runs are not recorded into the report of the run.
+func repeat(cfg config, fct func(cfg config) error) error {
+	for i := 0; i < cfg.warmup+cfg.count; i++ {
+		c := cfg
+		if i < cfg.warmup {
+			log.Printf("warm-up run %d/%d", i+1, cfg.warmup)
+			c.report = nil
+		}
+		err := fct(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runBench(ctx context.Context, id string, cfg config) error {
+	bench, ok := benchIDs[id]
 	if !ok {
 		return fmt.Errorf("no such OpenData example: %q", id)
 	}
 
 	log.Printf("running %q...", id)
-	beg := time.Now()
-	err := fct(fname)
-	end := time.Now()
-	log.Printf("running %q... [err=%v] delta=%v", id, err, end.Sub(beg))
+	rep := newProgress(id, cfg)
+	beg := newUsage()
+	hs, cf, nevts, err := run(progress.NewContext(ctx, rep), bench, cfg)
+	end := newUsage()
+	rep.Close()
+	log.Printf("running %q... [err=%v] entries=%d delta=%v", id, err, nevts, end.wall.Sub(beg.wall))
+	printIO(beg, end)
+
+	cfg.report.add(bench, cfg, nevts, hs, cf, beg, end, err)
 
+	if interrupted(err) {
+		log.Printf("%q interrupted after %d entries: partial results saved", id, nevts)
+		return err
+	}
 	if err != nil {
 		return fmt.Errorf("could not run bench %q: %w", id, err)
 	}
@@ -109,10 +366,190 @@ func runBench(id, fname string) error {
 	return err
 }
 
-func init() {
-	benchNames = make([]string, 0, len(benchIDs))
+// run runs the provided benchmark over the Events tree of the input file,
+// and saves the plot of the filled histograms.
+// run returns the filled histograms, the cut-flow and the number of
+// processed events.
+// When the context is canceled, the partial results are saved and run
+// returns the error of the context.
+func run(ctx context.Context, bench *Bench, cfg config) ([]hbook.Histogram, *CutFlow, int64, error) {
+	ana := bench.New()
+	if ana, ok := ana.(TreeAnalysis); ok {
+		if cfg.skim != nil {
+			return nil, nil, 0, fmt.Errorf("could not skim %q: analyses processing whole trees can not be skimmed", bench.Name)
+		}
+		t := &task{bench: bench, ana: ana, hs: ana.Book()}
+		if cfg.friend != nil {
+			if !cfg.rctx.IsAll() {
+				return nil, nil, 0, fmt.Errorf("friend trees require processing all the entries")
+			}
+			var err error
+			t.friend, err = cfg.friend.newFriendWriter(t)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+		}
+		err := processTree(ctx, t, cfg)
+		ferr := t.friend.close(bench, cfg, err)
+		if ferr != nil && (err == nil || interrupted(err)) {
+			return t.hs, ana.CutFlow(), t.nevts, ferr
+		}
+		return t.hs, ana.CutFlow(), t.nevts, finish(bench, ana, t.hs, t.nevts, cfg.out, err)
+	}
+
+	tasks, err := runTasks(ctx, []*Bench{bench}, cfg)
+	if err != nil && !interrupted(err) {
+		return nil, nil, 0, err
+	}
+
+	t := tasks[0]
+	if t.err != nil {
+		return t.hs, t.ana.CutFlow(), t.nevts, t.err
+	}
+
+	return t.hs, t.ana.CutFlow(), t.nevts, finish(t.bench, t.ana, t.hs, t.nevts, cfg.out, err)
+}
+
+// processTree feeds the entries of the Events trees of the input files
+// selected by the run context to the analysis of the provided task, one
+// file after the other, possibly checkpointed.
+func processTree(ctx context.Context, t *task, cfg config) error {
+	ch, err := openTree(cfg.fname)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	fmt.Printf("tree: %d entries\n", ch.entries())
+
+	ana := t.ana.(TreeAnalysis)
+	return cfg.ckpt.run(ctx, []*task{t}, ch, cfg, func(rctx RunContext) error {
+		n, err := processChain(ctx, ana, ch, rctx)
+		t.nevts += n
+		return err
+	})
+}
+
+// processChain feeds the entries of the chain selected by the run context
+// to the provided analysis, and returns the number of entries it was given.
+func processChain(ctx context.Context, ana TreeAnalysis, ch *chain, rctx RunContext) (int64, error) {
+	n := int64(0)
+	for i, tree := range ch.trees {
+		rctx := rctx.local(ch.offs[i], tree.Entries())
+		if rctx.Entries(tree.Entries()) == 0 {
+			continue
+		}
+		err := ctx.Err()
+		if err != nil {
+			return n, err
+		}
+		nevts, err := ana.ProcessTree(ctx, tree, rctx)
+		n += nevts
+		if err != nil {
+			return n, fmt.Errorf("could not process %q: %w", ch.names[i], err)
+		}
+	}
+
+	return n, nil
+}
+
+// finish finalizes an analysis which ran to completion or was interrupted
+// after nevts entries, and returns the error of its run.
+// The histograms and plots of an interrupted analysis are marked as partial.
+func finish(bench *Bench, ana Analysis, hs []hbook.Histogram, nevts int64, out *output, err error) error {
+	if err != nil && !interrupted(err) {
+		return err
+	}
+
+	partial := err != nil
+	if partial {
+		markPartial(hs, nevts)
+	}
+
+	ferr := finalize(bench, ana, hs, out, partial)
+	if ferr != nil {
+		return ferr
+	}
+
+	return err
+}
+
+// finalize prints a summary of the histograms filled by the analysis and
+// its cut-flow, saves their plot and records them into the output.
+func finalize(bench *Bench, ana Analysis, hs []hbook.Histogram, out *output, partial bool) error {
+	for _, h := range hs {
+		fmt.Printf("%s: %v\n", h.Name(), sumW(h))
+	}
+
+	cf := ana.CutFlow()
+	err := cf.writeTable(os.Stdout)
+	if err != nil {
+		return fmt.Errorf("could not print cut-flow: %w", err)
+	}
+
+	err = out.plot(bench, ana.Plot(), partial)
+	if err != nil {
+		return err
+	}
+
+	err = out.cutflow(bench, cf)
+	if err != nil {
+		return err
+	}
+	out.add(bench, hs)
+
+	return nil
+}
+
+// newProgress returns a reporter of the progress of the named run over
+// the entries of the input files, or nil if progress is not reported.
+// The reporter refreshes a status line when stderr is a terminal, and
+// writes a log line every progress.DefaultInterval otherwise.
+func newProgress(name string, cfg config) *progress.Reporter {
+	if !cfg.progress {
+		return nil
+	}
+
+	n := int64(0)
+	for _, f := range cfg.inputs {
+		n += f.entries
+	}
+
+	return progress.New(
+		os.Stderr, cfg.rctx.Entries(n),
+		progress.WithName(log.Prefix()+name),
+		progress.WithBytes(func() int64 { return atomic.LoadInt64(&bytesRead) }),
+	)
+}
+
+// saveBenchFmt writes the measured runs of the report in the Go benchmark
+// format to the named file.
+func saveBenchFmt(fname string, rep *Report) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	defer f.Close()
+
+	err = rep.writeBenchFmt(f)
+	if err != nil {
+		return fmt.Errorf("could not write benchmark results: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("could not close file: %w", err)
+	}
+
+	return nil
+}
+
+// sortedBenchNames returns the sorted list of registered benchmarks.
+func sortedBenchNames() []string {
+	names := make([]string, 0, len(benchIDs))
 	for k := range benchIDs {
-		benchNames = append(benchNames, k)
+		names = append(names, k)
 	}
-	sort.Strings(benchNames)
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,110 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"go-hep.org/x/hep/hbook"
+)
+
+// mergeHists adds the content of the src histograms to the dst ones.
+// dst and src histograms are matched by position and must have
+// the same binning.
+func mergeHists(dst, src []hbook.Histogram) error {
+	if len(dst) != len(src) {
+		return fmt.Errorf("histograms length mismatch (%d != %d)", len(dst), len(src))
+	}
+
+	for i := range dst {
+		var err error
+		switch d := dst[i].(type) {
+		case *hbook.H1D:
+			s, ok := src[i].(*hbook.H1D)
+			if !ok {
+				return fmt.Errorf("histogram type mismatch for %q (%T != %T)", d.Name(), dst[i], src[i])
+			}
+			err = mergeH1D(d, s)
+		case *hbook.H2D:
+			s, ok := src[i].(*hbook.H2D)
+			if !ok {
+				return fmt.Errorf("histogram type mismatch for %q (%T != %T)", d.Name(), dst[i], src[i])
+			}
+			err = mergeH2D(d, s)
+		default:
+			return fmt.Errorf("unknown histogram type %T", d)
+		}
+		if err != nil {
+			return fmt.Errorf("could not merge %q: %w", dst[i].Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// mergeH1D adds the content of src to dst.
+func mergeH1D(dst, src *hbook.H1D) error {
+	var (
+		d = &dst.Binning
+		s = &src.Binning
+	)
+	if len(d.Bins) != len(s.Bins) || d.XRange != s.XRange {
+		return fmt.Errorf("binning mismatch")
+	}
+	for i := range d.Bins {
+		if d.Bins[i].Range != s.Bins[i].Range {
+			return fmt.Errorf("binning mismatch (bin %d)", i)
+		}
+		addDist1D(&d.Bins[i].Dist, &s.Bins[i].Dist)
+	}
+	for i := range d.Outflows {
+		addDist1D(&d.Outflows[i], &s.Outflows[i])
+	}
+	addDist1D(&d.Dist, &s.Dist)
+
+	return nil
+}
+
+// mergeH2D adds the content of src to dst.
+func mergeH2D(dst, src *hbook.H2D) error {
+	var (
+		d = &dst.Binning
+		s = &src.Binning
+	)
+	if len(d.Bins) != len(s.Bins) || d.Nx != s.Nx || d.Ny != s.Ny ||
+		d.XRange != s.XRange || d.YRange != s.YRange {
+		return fmt.Errorf("binning mismatch")
+	}
+	for i := range d.Bins {
+		if d.Bins[i].XRange != s.Bins[i].XRange || d.Bins[i].YRange != s.Bins[i].YRange {
+			return fmt.Errorf("binning mismatch (bin %d)", i)
+		}
+		addDist2D(&d.Bins[i].Dist, &s.Bins[i].Dist)
+	}
+	for i := range d.Outflows {
+		addDist2D(&d.Outflows[i], &s.Outflows[i])
+	}
+	addDist2D(&d.Dist, &s.Dist)
+
+	return nil
+}
+
+func addDist0D(dst, src *hbook.Dist0D) {
+	dst.N += src.N
+	dst.SumW += src.SumW
+	dst.SumW2 += src.SumW2
+}
+
+func addDist1D(dst, src *hbook.Dist1D) {
+	addDist0D(&dst.Dist, &src.Dist)
+	dst.Stats.SumWX += src.Stats.SumWX
+	dst.Stats.SumWX2 += src.Stats.SumWX2
+}
+
+func addDist2D(dst, src *hbook.Dist2D) {
+	addDist1D(&dst.X, &src.X)
+	addDist1D(&dst.Y, &src.Y)
+	dst.Stats.SumWXY += src.Stats.SumWXY
+}
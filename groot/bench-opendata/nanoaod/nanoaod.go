@@ -0,0 +1,383 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nanoaod provides typed collections of the NanoAOD physics objects
+// of the OpenData benchmarks (muons, electrons and jets), and the operations
+// the benchmark analyses perform on them: selections, sorting, combinations,
+// angular distances, invariant masses and overlap removal.
+//
+// Collections are loaded from the columns of the Events tree (e.g. the
+// Muon_pt, Muon_eta, ... branches for Muons) with their Load method.
+package nanoaod // import "github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+
+import (
+	"math"
+	"sort"
+
+	"go-hep.org/x/hep/fmom"
+	"gonum.org/v1/gonum/stat/combin"
+)
+
+// Lepton is a NanoAOD muon or electron.
+type Lepton struct {
+	Pt     float32
+	Eta    float32
+	Phi    float32
+	Mass   float32
+	Charge int32
+}
+
+// P4 returns the 4-momentum of the lepton.
+func (lep *Lepton) P4() fmom.PtEtaPhiM {
+	return fmom.NewPtEtaPhiM(float64(lep.Pt), float64(lep.Eta), float64(lep.Phi), float64(lep.Mass))
+}
+
+// Jet is a NanoAOD jet.
+type Jet struct {
+	Pt   float32
+	Eta  float32
+	Phi  float32
+	Mass float32
+	Btag float32
+}
+
+// P4 returns the 4-momentum of the jet.
+func (jet *Jet) P4() fmom.PtEtaPhiM {
+	return fmom.NewPtEtaPhiM(float64(jet.Pt), float64(jet.Eta), float64(jet.Phi), float64(jet.Mass))
+}
+
+// Collection is a collection of physics objects.
+// Collection is implemented by Muons, Electrons and Jets.
+type Collection interface {
+	// Len returns the number of objects of the collection.
+	Len() int
+
+	// P4 returns the 4-momentum of the i-th object.
+	P4(i int) fmom.PtEtaPhiM
+
+	// Combinations returns the indices of all the combinations of k
+	// objects, in lexicographical order.
+	Combinations(k int) [][]int
+
+	// EtaPhi returns the pseudo-rapidity and azimuth of the i-th object.
+	EtaPhi(i int) (eta, phi float32)
+}
+
+// Leptons is a collection of charged leptons.
+// Leptons is implemented by Muons and Electrons.
+type Leptons interface {
+	Collection
+
+	// Charge returns the charge of the i-th lepton.
+	Charge(i int) int32
+}
+
+// Muons is a collection of muons, loaded from the Muon_* branches.
+type Muons []Lepton
+
+// Electrons is a collection of electrons, loaded from the Electron_*
+// branches.
+type Electrons []Lepton
+
+// Jets is a collection of jets, loaded from the Jet_* branches.
+type Jets []Jet
+
+// Load loads the muons from the columns of the Muon_pt, Muon_eta,
+// Muon_phi, Muon_mass and Muon_charge branches, reusing the memory of the
+// collection.
+// The fields of unread (nil) columns are left to zero.
+func (mus *Muons) Load(pt, eta, phi, mass []float32, charge []int32) {
+	*mus = loadLeptons(*mus, pt, eta, phi, mass, charge)
+}
+
+// Load loads the electrons from the columns of the Electron_pt,
+// Electron_eta, Electron_phi, Electron_mass and Electron_charge branches,
+// reusing the memory of the collection.
+// The fields of unread (nil) columns are left to zero.
+func (eles *Electrons) Load(pt, eta, phi, mass []float32, charge []int32) {
+	*eles = loadLeptons(*eles, pt, eta, phi, mass, charge)
+}
+
+// Load loads the jets from the columns of the Jet_pt, Jet_eta, Jet_phi,
+// Jet_mass and Jet_btag branches, reusing the memory of the collection.
+// The fields of unread (nil) columns are left to zero.
+func (jets *Jets) Load(pt, eta, phi, mass, btag []float32) {
+	n := maxLen(len(pt), len(eta), len(phi), len(mass), len(btag))
+	if cap(*jets) < n {
+		*jets = make(Jets, n)
+	}
+	*jets = (*jets)[:n]
+	for i := range *jets {
+		jet := &(*jets)[i]
+		jet.Pt = f32At(pt, i)
+		jet.Eta = f32At(eta, i)
+		jet.Phi = f32At(phi, i)
+		jet.Mass = f32At(mass, i)
+		jet.Btag = f32At(btag, i)
+	}
+}
+
+func loadLeptons(leps []Lepton, pt, eta, phi, mass []float32, charge []int32) []Lepton {
+	n := maxLen(len(pt), len(eta), len(phi), len(mass), len(charge))
+	if cap(leps) < n {
+		leps = make([]Lepton, n)
+	}
+	leps = leps[:n]
+	for i := range leps {
+		lep := &leps[i]
+		lep.Pt = f32At(pt, i)
+		lep.Eta = f32At(eta, i)
+		lep.Phi = f32At(phi, i)
+		lep.Mass = f32At(mass, i)
+		lep.Charge = i32At(charge, i)
+	}
+	return leps
+}
+
+func (mus Muons) Len() int                        { return len(mus) }
+func (mus Muons) P4(i int) fmom.PtEtaPhiM         { return mus[i].P4() }
+func (mus Muons) Charge(i int) int32              { return mus[i].Charge }
+func (mus Muons) EtaPhi(i int) (float32, float32) { return mus[i].Eta, mus[i].Phi }
+
+func (eles Electrons) Len() int                        { return len(eles) }
+func (eles Electrons) P4(i int) fmom.PtEtaPhiM         { return eles[i].P4() }
+func (eles Electrons) Charge(i int) int32              { return eles[i].Charge }
+func (eles Electrons) EtaPhi(i int) (float32, float32) { return eles[i].Eta, eles[i].Phi }
+
+func (jets Jets) Len() int                        { return len(jets) }
+func (jets Jets) P4(i int) fmom.PtEtaPhiM         { return jets[i].P4() }
+func (jets Jets) EtaPhi(i int) (float32, float32) { return jets[i].Eta, jets[i].Phi }
+
+// Filter returns a new collection of the muons for which keep returns true.
+func (mus Muons) Filter(keep func(mu *Lepton) bool) Muons {
+	return filterLeptons(mus, keep)
+}
+
+// Filter returns a new collection of the electrons for which keep returns
+// true.
+func (eles Electrons) Filter(keep func(ele *Lepton) bool) Electrons {
+	return filterLeptons(eles, keep)
+}
+
+// Filter returns a new collection of the jets for which keep returns true.
+func (jets Jets) Filter(keep func(jet *Jet) bool) Jets {
+	var o Jets
+	for i := range jets {
+		if keep(&jets[i]) {
+			o = append(o, jets[i])
+		}
+	}
+	return o
+}
+
+func filterLeptons(leps []Lepton, keep func(lep *Lepton) bool) []Lepton {
+	var o []Lepton
+	for i := range leps {
+		if keep(&leps[i]) {
+			o = append(o, leps[i])
+		}
+	}
+	return o
+}
+
+// Keep keeps the muons for which keep returns true, in place, without
+// allocating.
+func (mus *Muons) Keep(keep func(mu *Lepton) bool) {
+	*mus = keepLeptons(*mus, keep)
+}
+
+// Keep keeps the electrons for which keep returns true, in place, without
+// allocating.
+func (eles *Electrons) Keep(keep func(ele *Lepton) bool) {
+	*eles = keepLeptons(*eles, keep)
+}
+
+// Keep keeps the jets for which keep returns true, in place, without
+// allocating.
+func (jets *Jets) Keep(keep func(jet *Jet) bool) {
+	o := (*jets)[:0]
+	for i := range *jets {
+		if keep(&(*jets)[i]) {
+			o = append(o, (*jets)[i])
+		}
+	}
+	*jets = o
+}
+
+func keepLeptons(leps []Lepton, keep func(lep *Lepton) bool) []Lepton {
+	o := leps[:0]
+	for i := range leps {
+		if keep(&leps[i]) {
+			o = append(o, leps[i])
+		}
+	}
+	return o
+}
+
+// Count returns the number of muons for which keep returns true.
+func (mus Muons) Count(keep func(mu *Lepton) bool) int {
+	return countLeptons(mus, keep)
+}
+
+// Count returns the number of electrons for which keep returns true.
+func (eles Electrons) Count(keep func(ele *Lepton) bool) int {
+	return countLeptons(eles, keep)
+}
+
+// Count returns the number of jets for which keep returns true.
+func (jets Jets) Count(keep func(jet *Jet) bool) int {
+	n := 0
+	for i := range jets {
+		if keep(&jets[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+func countLeptons(leps []Lepton, keep func(lep *Lepton) bool) int {
+	n := 0
+	for i := range leps {
+		if keep(&leps[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+// SortByPt sorts the muons by decreasing pT, in place.
+func (mus Muons) SortByPt() {
+	sort.SliceStable(mus, func(i, j int) bool { return mus[i].Pt > mus[j].Pt })
+}
+
+// SortByPt sorts the electrons by decreasing pT, in place.
+func (eles Electrons) SortByPt() {
+	sort.SliceStable(eles, func(i, j int) bool { return eles[i].Pt > eles[j].Pt })
+}
+
+// SortByPt sorts the jets by decreasing pT, in place.
+func (jets Jets) SortByPt() {
+	sort.SliceStable(jets, func(i, j int) bool { return jets[i].Pt > jets[j].Pt })
+}
+
+// Combinations returns the indices of all the combinations of k muons, in
+// lexicographical order.
+func (mus Muons) Combinations(k int) [][]int { return combinations(len(mus), k) }
+
+// Combinations returns the indices of all the combinations of k electrons,
+// in lexicographical order.
+func (eles Electrons) Combinations(k int) [][]int { return combinations(len(eles), k) }
+
+// Combinations returns the indices of all the combinations of k jets, in
+// lexicographical order.
+func (jets Jets) Combinations(k int) [][]int { return combinations(len(jets), k) }
+
+// combinations returns all the combinations of k elements among n, or nil
+// if there are less than k elements.
+func combinations(n, k int) [][]int {
+	if n < k || k <= 0 {
+		return nil
+	}
+	return combin.Combinations(n, k)
+}
+
+// RemoveOverlaps returns a new collection of the jets farther than dr, in
+// (eta, phi), from all the objects of the provided collections.
+func (jets Jets) RemoveOverlaps(dr float64, others ...Collection) Jets {
+	dr2 := dr * dr
+	return jets.Filter(func(jet *Jet) bool {
+		return isolated(jet, dr2, others)
+	})
+}
+
+// KeepIsolated keeps the jets farther than dr, in (eta, phi), from all the
+// objects of the provided collections, in place.
+// KeepIsolated is the in place version of RemoveOverlaps: it does not
+// allocate when the collections are provided as pointers (e.g. *Muons).
+func (jets *Jets) KeepIsolated(dr float64, others ...Collection) {
+	dr2 := dr * dr
+	o := (*jets)[:0]
+	for i := range *jets {
+		if isolated(&(*jets)[i], dr2, others) {
+			o = append(o, (*jets)[i])
+		}
+	}
+	*jets = o
+}
+
+// isolated returns whether the jet is farther than sqrt(dr2), in
+// (eta, phi), from all the objects of the provided collections.
+func isolated(jet *Jet, dr2 float64, others []Collection) bool {
+	for _, c := range others {
+		for i := 0; i < c.Len(); i++ {
+			eta, phi := c.EtaPhi(i)
+			if deltaR2(jet.Eta, jet.Phi, eta, phi) <= dr2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// DeltaR returns the distance in (eta, phi) between the i1-th object of c1
+// and the i2-th object of c2.
+func DeltaR(c1 Collection, i1 int, c2 Collection, i2 int) float64 {
+	var (
+		eta1, phi1 = c1.EtaPhi(i1)
+		eta2, phi2 = c2.EtaPhi(i2)
+	)
+	return math.Sqrt(deltaR2(eta1, phi1, eta2, phi2))
+}
+
+func deltaR2(eta1, phi1, eta2, phi2 float32) float64 {
+	const twopi = 2 * math.Pi
+
+	dphi := -math.Remainder(float64(phi1-phi2), twopi)
+	deta := float64(eta1 - eta2)
+	return dphi*dphi + deta*deta
+}
+
+// Sum returns the sum of the 4-momenta of the objects idx of c.
+func Sum(c Collection, idx ...int) fmom.PtEtaPhiM {
+	if len(idx) == 0 {
+		return fmom.PtEtaPhiM{}
+	}
+	p := c.P4(idx[0])
+	var sum fmom.P4 = &p
+	for _, i := range idx[1:] {
+		pi := c.P4(i)
+		sum = fmom.Add(sum, &pi)
+	}
+	return *sum.(*fmom.PtEtaPhiM)
+}
+
+// InvMass returns the invariant mass of the objects idx of c.
+func InvMass(c Collection, idx ...int) float64 {
+	p := Sum(c, idx...)
+	return p.M()
+}
+
+func maxLen(ns ...int) int {
+	max := 0
+	for _, n := range ns {
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+func f32At(vs []float32, i int) float32 {
+	if i < len(vs) {
+		return vs[i]
+	}
+	return 0
+}
+
+func i32At(vs []int32, i int) int32 {
+	if i < len(vs) {
+		return vs[i]
+	}
+	return 0
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nanoaod
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"go-hep.org/x/hep/fmom"
+)
+
+func TestLoad(t *testing.T) {
+	var mus Muons
+	mus.Load([]float32{10, 20}, []float32{1, 2}, nil, nil, []int32{-1, +1})
+	want := Muons{{Pt: 10, Eta: 1, Charge: -1}, {Pt: 20, Eta: 2, Charge: +1}}
+	if !reflect.DeepEqual(mus, want) {
+		t.Fatalf("invalid muons:\ngot= %+v\nwant=%+v", mus, want)
+	}
+
+	// reloading reuses the memory of the collection.
+	mus.Load([]float32{30}, nil, nil, nil, nil)
+	if want := (Muons{{Pt: 30}}); !reflect.DeepEqual(mus, want) || cap(mus) != 2 {
+		t.Fatalf("invalid reloaded muons: %+v (cap=%d)", mus, cap(mus))
+	}
+
+	var eles Electrons
+	eles.Load(nil, nil, nil, nil, nil)
+	if len(eles) != 0 {
+		t.Fatalf("invalid electrons: %+v", eles)
+	}
+
+	var jets Jets
+	jets.Load([]float32{40, 50}, nil, []float32{3}, nil, []float32{0.5, 0.9})
+	if want := (Jets{{Pt: 40, Phi: 3, Btag: 0.5}, {Pt: 50, Btag: 0.9}}); !reflect.DeepEqual(jets, want) {
+		t.Fatalf("invalid jets:\ngot= %+v\nwant=%+v", jets, want)
+	}
+}
+
+func TestFilterSort(t *testing.T) {
+	jets := Jets{{Pt: 20, Eta: 1}, {Pt: 40, Eta: 2}, {Pt: 10, Eta: 3}, {Pt: 40, Eta: 4}}
+	hard := jets.Filter(func(jet *Jet) bool { return jet.Pt > 15 })
+	hard.SortByPt()
+	if want := (Jets{{Pt: 40, Eta: 2}, {Pt: 40, Eta: 4}, {Pt: 20, Eta: 1}}); !reflect.DeepEqual(hard, want) {
+		t.Fatalf("invalid jets:\ngot= %+v\nwant=%+v", hard, want)
+	}
+	if jets[0].Eta != 1 || len(jets) != 4 {
+		t.Fatalf("filter modified its input: %+v", jets)
+	}
+
+	eles := Electrons{{Pt: 5}, {Pt: 25}}.Filter(func(ele *Lepton) bool { return ele.Pt > 30 })
+	if len(eles) != 0 {
+		t.Fatalf("invalid electrons: %+v", eles)
+	}
+}
+
+func TestKeepCount(t *testing.T) {
+	jets := Jets{{Pt: 20, Eta: 1}, {Pt: 40, Eta: 2}, {Pt: 10, Eta: 3}, {Pt: 40, Eta: 4}}
+	hard := func(jet *Jet) bool { return jet.Pt > 15 }
+	if got, want := jets.Count(hard), 3; got != want {
+		t.Fatalf("invalid number of jets: got=%d, want=%d", got, want)
+	}
+
+	mem := &jets[0]
+	jets.Keep(hard)
+	if want := (Jets{{Pt: 20, Eta: 1}, {Pt: 40, Eta: 2}, {Pt: 40, Eta: 4}}); !reflect.DeepEqual(jets, want) {
+		t.Fatalf("invalid jets:\ngot= %+v\nwant=%+v", jets, want)
+	}
+	if &jets[0] != mem {
+		t.Fatalf("keep did not reuse the memory of the collection")
+	}
+
+	mus := Muons{{Pt: 5}, {Pt: 25}, {Pt: 15}}
+	soft := func(mu *Lepton) bool { return mu.Pt < 20 }
+	if got, want := mus.Count(soft), 2; got != want {
+		t.Fatalf("invalid number of muons: got=%d, want=%d", got, want)
+	}
+	mus.Keep(soft)
+	if want := (Muons{{Pt: 5}, {Pt: 15}}); !reflect.DeepEqual(mus, want) {
+		t.Fatalf("invalid muons:\ngot= %+v\nwant=%+v", mus, want)
+	}
+
+	eles := Electrons{{Pt: 5}, {Pt: 25}}
+	eles.Keep(func(ele *Lepton) bool { return ele.Pt > 30 })
+	if len(eles) != 0 {
+		t.Fatalf("invalid electrons: %+v", eles)
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	mus := Muons{{}, {}, {}}
+	for _, tc := range []struct {
+		k    int
+		want [][]int
+	}{
+		{k: 0, want: nil},
+		{k: 1, want: [][]int{{0}, {1}, {2}}},
+		{k: 2, want: [][]int{{0, 1}, {0, 2}, {1, 2}}},
+		{k: 3, want: [][]int{{0, 1, 2}}},
+		{k: 4, want: nil},
+	} {
+		got := mus.Combinations(tc.k)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("k=%d: invalid combinations: got=%v, want=%v", tc.k, got, tc.want)
+		}
+	}
+	if got := (Jets{}).Combinations(2); got != nil {
+		t.Fatalf("invalid combinations of an empty collection: %v", got)
+	}
+}
+
+func TestDeltaR(t *testing.T) {
+	var (
+		jets = Jets{{Eta: 0.5, Phi: 3.0}}
+		mus  = Muons{{Eta: 0.5, Phi: -3.0}, {Eta: 1.5, Phi: 3.0}}
+	)
+	for _, tc := range []struct {
+		i    int
+		want float64
+	}{
+		{i: 0, want: 2*math.Pi - 6}, // across the phi = pi boundary
+		{i: 1, want: 1},
+	} {
+		got := DeltaR(jets, 0, mus, tc.i)
+		if math.Abs(got-tc.want) > 1e-6 {
+			t.Fatalf("muon %d: invalid delta-R: got=%v, want=%v", tc.i, got, tc.want)
+		}
+	}
+
+	iso := Jets{{Pt: 30, Phi: 1}, {Pt: 40, Phi: 2}, {Pt: 50, Phi: -3.1}}.RemoveOverlaps(0.4,
+		Muons{{Phi: 1.3}},
+		Electrons{{Phi: 3.1}},
+	)
+	if want := (Jets{{Pt: 40, Phi: 2}}); !reflect.DeepEqual(iso, want) {
+		t.Fatalf("invalid isolated jets:\ngot= %+v\nwant=%+v", iso, want)
+	}
+
+	jets = Jets{{Pt: 30, Phi: 1}, {Pt: 40, Phi: 2}, {Pt: 50, Phi: -3.1}}
+	jets.KeepIsolated(0.4, Muons{{Phi: 1.3}}, Electrons{{Phi: 3.1}})
+	if !reflect.DeepEqual(jets, iso) {
+		t.Fatalf("invalid isolated jets kept in place:\ngot= %+v\nwant=%+v", jets, iso)
+	}
+}
+
+func TestInvMass(t *testing.T) {
+	jets := Jets{
+		{Pt: 50, Eta: 0.1, Phi: 0.2, Mass: 5},
+		{Pt: 40, Eta: -1.2, Phi: 2.5, Mass: 8},
+		{Pt: 30, Eta: 2.1, Phi: -1.5, Mass: 3},
+	}
+
+	var (
+		p1 = jets.P4(0)
+		p2 = jets.P4(1)
+		p3 = jets.P4(2)
+	)
+	want := fmom.Add(fmom.Add(&p1, &p2), &p3)
+
+	got := Sum(jets, 0, 1, 2)
+	if !closeTo(got.Pt(), want.Pt()) || !closeTo(got.Eta(), want.Eta()) || !closeTo(got.M(), want.M()) {
+		t.Fatalf("invalid sum: got=%v, want=%v", got, want)
+	}
+	if got, want := InvMass(jets, 0, 1, 2), want.M(); !closeTo(got, want) {
+		t.Fatalf("invalid invariant mass: got=%v, want=%v", got, want)
+	}
+	if got, want := InvMass(jets, 0, 2), fmom.InvMass(&p1, &p3); !closeTo(got, want) {
+		t.Fatalf("invalid pair mass: got=%v, want=%v", got, want)
+	}
+	if got := InvMass(jets); got != 0 {
+		t.Fatalf("invalid invariant mass of no object: %v", got)
+	}
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want))
+}
+
+func BenchmarkFilter(b *testing.B) {
+	jets := Jets{{Pt: 20, Eta: 1}, {Pt: 40, Eta: 2}, {Pt: 10, Eta: 3}, {Pt: 40, Eta: 4}}
+	mus := Muons{{Pt: 25, Phi: 1.3}}
+	hard := func(jet *Jet) bool { return jet.Pt > 15 }
+
+	b.Run("Filter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = jets.Filter(hard).RemoveOverlaps(0.4, mus)
+		}
+	})
+
+	b.Run("Keep", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make(Jets, len(jets))
+		for i := 0; i < b.N; i++ {
+			o := buf[:copy(buf, jets)]
+			o.Keep(hard)
+			o.KeepIsolated(0.4, &mus)
+		}
+	})
+
+	b.Run("Count", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = jets.Count(hard)
+		}
+	})
+}
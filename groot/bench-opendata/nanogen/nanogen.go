@@ -0,0 +1,511 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nanogen generates synthetic NanoAOD-like events, stored in an
+// Events tree with the same branches and types than the CMS Run2012B
+// OpenData files used by bench-opendata.
+//
+// Events are made of a configurable mix of:
+//   - Z bosons decaying into a muon or electron pair,
+//   - top-like trijets (t -> bW -> bqq'),
+//   - additional muons, electrons and jets, with a b-tag discriminator
+//     distributed differently for light and b jets.
+package nanogen // import "github.com/go-hep/examples/groot/bench-opendata/nanogen"
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+const (
+	muonMass = 0.105658
+	eleMass  = 0.000511
+	bMass    = 4.8
+	wMass    = 80.4
+	wWidth   = 2.1
+)
+
+// Config holds the parameters of the generated physics.
+type Config struct {
+	Seed uint64 // seed of the random number generator
+
+	Muons     float64 // mean number of additional muons per event
+	Electrons float64 // mean number of additional electrons per event
+	Jets      float64 // mean number of additional jets per event
+	SumEt     float64 // mean scalar sum of the transverse energy of the unobserved activity
+
+	Z   Resonance // Z -> ll peak
+	Top Resonance // top-like trijets
+
+	BTag BTag // b-tag discriminator distribution
+}
+
+// validate returns an error if the configuration can not be generated.
+func (cfg Config) validate() error {
+	if cfg.Top.Frac > 0 && cfg.Top.Mass <= wMass+2*bMass {
+		return fmt.Errorf("nanogen: top mass too small to decay into bW (%v GeV)", cfg.Top.Mass)
+	}
+	return nil
+}
+
+// Resonance describes a resonance decaying in some of the events.
+type Resonance struct {
+	Frac  float64 // fraction of events with a resonance
+	Mass  float64 // mass of the resonance
+	Width float64 // width of the resonance
+	Pt    float64 // mean transverse momentum of the resonance
+}
+
+// BTag describes the distribution of the b-tag discriminator of jets,
+// as Beta distributions for light and b jets.
+type BTag struct {
+	Frac  float64    // fraction of additional jets originating from a b quark
+	Light [2]float64 // (alpha, beta) parameters of the light jets distribution
+	Heavy [2]float64 // (alpha, beta) parameters of the b jets distribution
+}
+
+// Default returns the default configuration of the generator.
+func Default() Config {
+	return Config{
+		Seed:      1234,
+		Muons:     0.8,
+		Electrons: 0.5,
+		Jets:      2.5,
+		SumEt:     250,
+		Z: Resonance{
+			Frac:  0.2,
+			Mass:  91.1876,
+			Width: 2.4952,
+			Pt:    10,
+		},
+		Top: Resonance{
+			Frac:  0.2,
+			Mass:  172.5,
+			Width: 1.4,
+			Pt:    50,
+		},
+		BTag: BTag{
+			Frac:  0.1,
+			Light: [2]float64{1, 8},
+			Heavy: [2]float64{5, 1.5},
+		},
+	}
+}
+
+// Generate creates the named ROOT file, with the provided file options,
+// and fills its Events tree with nevts generated events.
+//
+// groot fails to compress a basket whose compressed payload is larger than
+// the uncompressed one, as may happen for the small baskets of random floats
+// of files with a few hundred events.
+// A compressed file that can not be written is generated again, without
+// compression.
+// The named file is removed if it can not be generated.
+func Generate(fname string, nevts int64, cfg Config, opts ...riofs.FileOption) error {
+	err := cfg.validate()
+	if err != nil {
+		return err
+	}
+
+	compressed, err := generate(fname, nevts, cfg, opts...)
+	if err != nil && compressed {
+		opts = append(opts[:len(opts):len(opts)], riofs.WithoutCompression())
+		_, err = generate(fname, nevts, cfg, opts...)
+		if err != nil {
+			return fmt.Errorf("nanogen: could not generate %q, with or without compression: %w", fname, err)
+		}
+	}
+	return err
+}
+
+// generate creates the named ROOT file, fills it with nevts generated
+// events, and returns whether the file was compressed.
+// generate removes the file if it can not be written.
+func generate(fname string, nevts int64, cfg Config, opts ...riofs.FileOption) (compressed bool, err error) {
+	f, err := groot.Create(fname, opts...)
+	if err != nil {
+		return false, fmt.Errorf("nanogen: could not create ROOT file: %w", err)
+	}
+	compressed = f.Compression() != 0
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(fname)
+		}
+	}()
+
+	err = Write(f, nevts, cfg)
+	if err != nil {
+		return compressed, err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return compressed, fmt.Errorf("nanogen: could not close ROOT file: %w", err)
+	}
+
+	return compressed, nil
+}
+
+// Write fills an Events tree, created under the provided directory,
+// with nevts generated events.
+func Write(dir riofs.Directory, nevts int64, cfg Config) error {
+	err := cfg.validate()
+	if err != nil {
+		return err
+	}
+
+	var evt event
+	tree, err := rtree.NewWriter(dir, "Events", evt.wvars())
+	if err != nil {
+		return fmt.Errorf("nanogen: could not create tree writer: %w", err)
+	}
+	defer tree.Close()
+
+	gen := newGenerator(cfg)
+	for i := int64(0); i < nevts; i++ {
+		gen.generate(&evt)
+		_, err = tree.Write()
+		if err != nil {
+			return fmt.Errorf("nanogen: could not write event %d: %w", i, err)
+		}
+	}
+
+	err = tree.Close()
+	if err != nil {
+		return fmt.Errorf("nanogen: could not close tree writer: %w", err)
+	}
+
+	return nil
+}
+
+// particle is a generated particle.
+type particle struct {
+	p      fmom.PxPyPzE
+	charge int32
+	btag   float32
+}
+
+// collection holds the columns of a NanoAOD collection.
+type collection struct {
+	n      uint32
+	pt     []float32
+	eta    []float32
+	phi    []float32
+	mass   []float32
+	charge []int32
+	btag   []float32
+}
+
+func newCollection() collection {
+	return collection{
+		pt:     []float32{},
+		eta:    []float32{},
+		phi:    []float32{},
+		mass:   []float32{},
+		charge: []int32{},
+		btag:   []float32{},
+	}
+}
+
+// fill fills the columns of the collection with the provided particles,
+// sorted by decreasing transverse momentum.
+func (c *collection) fill(ps []particle) {
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].p.Pt() > ps[j].p.Pt()
+	})
+
+	c.n = uint32(len(ps))
+	c.pt = c.pt[:0]
+	c.eta = c.eta[:0]
+	c.phi = c.phi[:0]
+	c.mass = c.mass[:0]
+	c.charge = c.charge[:0]
+	c.btag = c.btag[:0]
+	for i := range ps {
+		p := &ps[i].p
+		c.pt = append(c.pt, float32(p.Pt()))
+		c.eta = append(c.eta, float32(p.Eta()))
+		c.phi = append(c.phi, float32(p.Phi()))
+		c.mass = append(c.mass, float32(p.M()))
+		c.charge = append(c.charge, ps[i].charge)
+		c.btag = append(c.btag, ps[i].btag)
+	}
+}
+
+// event holds the columns of a NanoAOD event.
+type event struct {
+	muons collection
+	eles  collection
+	jets  collection
+
+	metPt    float32
+	metPhi   float32
+	metSumEt float32
+}
+
+func (evt *event) wvars() []rtree.WriteVar {
+	var (
+		mus  = &evt.muons
+		eles = &evt.eles
+		jets = &evt.jets
+	)
+	*mus = newCollection()
+	*eles = newCollection()
+	*jets = newCollection()
+
+	return []rtree.WriteVar{
+		{Name: "nMuon", Value: &mus.n},
+		{Name: "Muon_pt", Value: &mus.pt, Count: "nMuon"},
+		{Name: "Muon_eta", Value: &mus.eta, Count: "nMuon"},
+		{Name: "Muon_phi", Value: &mus.phi, Count: "nMuon"},
+		{Name: "Muon_mass", Value: &mus.mass, Count: "nMuon"},
+		{Name: "Muon_charge", Value: &mus.charge, Count: "nMuon"},
+		{Name: "nElectron", Value: &eles.n},
+		{Name: "Electron_pt", Value: &eles.pt, Count: "nElectron"},
+		{Name: "Electron_eta", Value: &eles.eta, Count: "nElectron"},
+		{Name: "Electron_phi", Value: &eles.phi, Count: "nElectron"},
+		{Name: "Electron_mass", Value: &eles.mass, Count: "nElectron"},
+		{Name: "Electron_charge", Value: &eles.charge, Count: "nElectron"},
+		{Name: "nJet", Value: &jets.n},
+		{Name: "Jet_pt", Value: &jets.pt, Count: "nJet"},
+		{Name: "Jet_eta", Value: &jets.eta, Count: "nJet"},
+		{Name: "Jet_phi", Value: &jets.phi, Count: "nJet"},
+		{Name: "Jet_mass", Value: &jets.mass, Count: "nJet"},
+		{Name: "Jet_btag", Value: &jets.btag, Count: "nJet"},
+		{Name: "MET_pt", Value: &evt.metPt},
+		{Name: "MET_phi", Value: &evt.metPhi},
+		{Name: "MET_sumet", Value: &evt.metSumEt},
+	}
+}
+
+// generator generates events.
+type generator struct {
+	cfg Config
+	rnd *rand.Rand
+
+	nmuons distuv.Poisson
+	neles  distuv.Poisson
+	njets  distuv.Poisson
+	light  distuv.Beta
+	heavy  distuv.Beta
+
+	muons []particle
+	eles  []particle
+	jets  []particle
+}
+
+func newGenerator(cfg Config) *generator {
+	src := rand.NewSource(cfg.Seed)
+	return &generator{
+		cfg:    cfg,
+		rnd:    rand.New(src),
+		nmuons: distuv.Poisson{Lambda: cfg.Muons, Src: src},
+		neles:  distuv.Poisson{Lambda: cfg.Electrons, Src: src},
+		njets:  distuv.Poisson{Lambda: cfg.Jets, Src: src},
+		light:  distuv.Beta{Alpha: cfg.BTag.Light[0], Beta: cfg.BTag.Light[1], Src: src},
+		heavy:  distuv.Beta{Alpha: cfg.BTag.Heavy[0], Beta: cfg.BTag.Heavy[1], Src: src},
+	}
+}
+
+func (gen *generator) generate(evt *event) {
+	gen.muons = gen.muons[:0]
+	gen.eles = gen.eles[:0]
+	gen.jets = gen.jets[:0]
+
+	if gen.cfg.Z.Frac > 0 && gen.rnd.Float64() < gen.cfg.Z.Frac {
+		gen.genZ()
+	}
+
+	if gen.cfg.Top.Frac > 0 && gen.rnd.Float64() < gen.cfg.Top.Frac {
+		gen.genTop()
+	}
+
+	for i, n := 0, gen.poisson(gen.nmuons); i < n; i++ {
+		gen.muons = append(gen.muons, gen.lepton(muonMass, 2.4))
+	}
+	for i, n := 0, gen.poisson(gen.neles); i < n; i++ {
+		gen.eles = append(gen.eles, gen.lepton(eleMass, 2.5))
+	}
+	for i, n := 0, gen.poisson(gen.njets); i < n; i++ {
+		gen.jets = append(gen.jets, gen.jet())
+	}
+
+	var (
+		sumet = gen.rnd.ExpFloat64() * gen.cfg.SumEt
+		px    = gen.rnd.NormFloat64() * 0.5 * math.Sqrt(sumet)
+		py    = gen.rnd.NormFloat64() * 0.5 * math.Sqrt(sumet)
+	)
+	for _, ps := range [][]particle{gen.muons, gen.eles, gen.jets} {
+		for i := range ps {
+			p := &ps[i].p
+			sumet += p.Pt()
+			px -= p.Px()
+			py -= p.Py()
+		}
+	}
+
+	evt.muons.fill(gen.muons)
+	evt.eles.fill(gen.eles)
+	evt.jets.fill(gen.jets)
+	evt.metPt = float32(math.Hypot(px, py))
+	evt.metPhi = float32(math.Atan2(py, px))
+	evt.metSumEt = float32(sumet)
+}
+
+func (gen *generator) poisson(dist distuv.Poisson) int {
+	if dist.Lambda <= 0 {
+		return 0
+	}
+	return int(dist.Rand())
+}
+
+// genZ generates a Z boson decaying into a muon or an electron pair.
+func (gen *generator) genZ() {
+	var (
+		z       = gen.resonance(gen.cfg.Z)
+		mass    = muonMass
+		leptons = &gen.muons
+	)
+	if gen.rnd.Intn(2) == 1 {
+		mass = eleMass
+		leptons = &gen.eles
+	}
+	p1, p2 := gen.decay(z, mass, mass)
+	charge := int32(2*gen.rnd.Intn(2) - 1)
+	*leptons = append(*leptons,
+		particle{p: p1, charge: +charge},
+		particle{p: p2, charge: -charge},
+	)
+}
+
+// genTop generates a top quark decaying into a b quark and a W boson,
+// the latter decaying into two light quarks.
+// The 3 quarks are reconstructed as jets.
+func (gen *generator) genTop() {
+	var (
+		top    = gen.resonance(gen.cfg.Top)
+		mw     = gen.breitWigner(wMass, wWidth, top.M()-bMass)
+		b, w   = gen.decay(top, bMass, mw)
+		q1, q2 = gen.decay(w, 0, 0)
+	)
+	gen.jets = append(gen.jets,
+		particle{p: b, btag: float32(gen.heavy.Rand())},
+		particle{p: q1, btag: float32(gen.light.Rand())},
+		particle{p: q2, btag: float32(gen.light.Rand())},
+	)
+}
+
+// lepton generates an additional lepton.
+func (gen *generator) lepton(mass, etaMax float64) particle {
+	var (
+		pt  = 5 + gen.rnd.ExpFloat64()*15
+		eta = (2*gen.rnd.Float64() - 1) * etaMax
+		phi = (2*gen.rnd.Float64() - 1) * math.Pi
+	)
+	return particle{
+		p:      pxpypze(pt, eta, phi, mass),
+		charge: int32(2*gen.rnd.Intn(2) - 1),
+	}
+}
+
+// jet generates an additional jet.
+func (gen *generator) jet() particle {
+	var (
+		pt   = 15 + gen.rnd.ExpFloat64()*20
+		eta  = gen.rnd.NormFloat64() * 2
+		phi  = (2*gen.rnd.Float64() - 1) * math.Pi
+		mass = pt * (0.05 + 0.15*gen.rnd.Float64())
+		btag = gen.light.Rand()
+	)
+	if gen.rnd.Float64() < gen.cfg.BTag.Frac {
+		btag = gen.heavy.Rand()
+	}
+	return particle{
+		p:    pxpypze(pt, eta, phi, mass),
+		btag: float32(btag),
+	}
+}
+
+// resonance generates the 4-momentum of a resonance.
+func (gen *generator) resonance(r Resonance) fmom.PxPyPzE {
+	var (
+		m   = gen.breitWigner(r.Mass, r.Width, 2*r.Mass)
+		pt  = gen.rnd.ExpFloat64() * r.Pt
+		y   = gen.rnd.NormFloat64() * 1.2
+		phi = (2*gen.rnd.Float64() - 1) * math.Pi
+		mt  = math.Hypot(pt, m)
+	)
+	return fmom.NewPxPyPzE(pt*math.Cos(phi), pt*math.Sin(phi), mt*math.Sinh(y), mt*math.Cosh(y))
+}
+
+// breitWigner returns a mass distributed according to a (non-relativistic)
+// Breit-Wigner of the provided mass and width, in [mass/2, max).
+func (gen *generator) breitWigner(mass, width, max float64) float64 {
+	for {
+		m := mass + 0.5*width*math.Tan(math.Pi*(gen.rnd.Float64()-0.5))
+		if 0.5*mass <= m && m < max {
+			return m
+		}
+	}
+}
+
+// decay returns the 4-momenta of the products of the isotropic 2-body decay
+// of a particle of 4-momentum p into particles of masses m1 and m2.
+func (gen *generator) decay(p fmom.PxPyPzE, m1, m2 float64) (fmom.PxPyPzE, fmom.PxPyPzE) {
+	var (
+		m   = p.M()
+		q   = math.Sqrt((m*m-(m1+m2)*(m1+m2))*(m*m-(m1-m2)*(m1-m2))) / (2 * m)
+		cth = 2*gen.rnd.Float64() - 1
+		sth = math.Sqrt(1 - cth*cth)
+		phi = 2 * math.Pi * gen.rnd.Float64()
+
+		qx = q * sth * math.Cos(phi)
+		qy = q * sth * math.Sin(phi)
+		qz = q * cth
+
+		p1 = fmom.NewPxPyPzE(+qx, +qy, +qz, math.Hypot(q, m1))
+		p2 = fmom.NewPxPyPzE(-qx, -qy, -qz, math.Hypot(q, m2))
+	)
+	return boost(p1, p), boost(p2, p)
+}
+
+// boost returns the 4-momentum p, given in the rest frame of a particle
+// of 4-momentum frame, in the frame where that particle has 4-momentum frame.
+func boost(p, frame fmom.PxPyPzE) fmom.PxPyPzE {
+	var (
+		bx = frame.Px() / frame.E()
+		by = frame.Py() / frame.E()
+		bz = frame.Pz() / frame.E()
+		b2 = bx*bx + by*by + bz*bz
+		bp = bx*p.Px() + by*p.Py() + bz*p.Pz()
+	)
+	if b2 <= 0 {
+		return p
+	}
+	var (
+		gamma  = 1 / math.Sqrt(1-b2)
+		gamma2 = (gamma - 1) / b2
+	)
+	return fmom.NewPxPyPzE(
+		p.Px()+gamma2*bp*bx+gamma*bx*p.E(),
+		p.Py()+gamma2*bp*by+gamma*by*p.E(),
+		p.Pz()+gamma2*bp*bz+gamma*bz*p.E(),
+		gamma*(p.E()+bp),
+	)
+}
+
+func pxpypze(pt, eta, phi, m float64) fmom.PxPyPzE {
+	p := fmom.NewPtEtaPhiM(pt, eta, phi, m)
+	return fmom.NewPxPyPzE(p.Px(), p.Py(), p.Pz(), p.E())
+}
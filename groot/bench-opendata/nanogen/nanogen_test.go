@@ -0,0 +1,223 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nanogen_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-hep/examples/groot/bench-opendata/nanogen"
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nanogen-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	const nevts = 1000
+
+	cfg := nanogen.Default()
+	cfg.Muons = 0
+	cfg.Electrons = 0
+	cfg.Jets = 0
+	cfg.Z.Frac = 1
+	cfg.Top.Frac = 0
+
+	fname := filepath.Join(dir, "events.root")
+	err = nanogen.Generate(fname, nevts, cfg)
+	if err != nil {
+		t.Fatalf("could not generate events: %+v", err)
+	}
+
+	f, err := groot.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	o, err := f.Get("Events")
+	if err != nil {
+		t.Fatalf("could not retrieve tree: %+v", err)
+	}
+	tree := o.(rtree.Tree)
+
+	if got, want := tree.Entries(), int64(nevts); got != want {
+		t.Fatalf("invalid number of entries: got=%d, want=%d", got, want)
+	}
+
+	var names []string
+	for _, b := range tree.Branches() {
+		names = append(names, b.Name())
+	}
+	want := []string{
+		"nMuon", "Muon_pt", "Muon_eta", "Muon_phi", "Muon_mass", "Muon_charge",
+		"nElectron", "Electron_pt", "Electron_eta", "Electron_phi", "Electron_mass", "Electron_charge",
+		"nJet", "Jet_pt", "Jet_eta", "Jet_phi", "Jet_mass", "Jet_btag",
+		"MET_pt", "MET_phi", "MET_sumet",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("invalid branches:\ngot= %q\nwant=%q", names, want)
+	}
+
+	var (
+		pt     []float32
+		eta    []float32
+		phi    []float32
+		mass   []float32
+		charge []int32
+		ele    []float32
+	)
+	sc, err := rtree.NewScannerVars(tree,
+		rtree.ScanVar{Name: "Muon_pt", Value: &pt},
+		rtree.ScanVar{Name: "Muon_eta", Value: &eta},
+		rtree.ScanVar{Name: "Muon_phi", Value: &phi},
+		rtree.ScanVar{Name: "Muon_mass", Value: &mass},
+		rtree.ScanVar{Name: "Muon_charge", Value: &charge},
+		rtree.ScanVar{Name: "Electron_pt", Value: &ele},
+	)
+	if err != nil {
+		t.Fatalf("could not create scanner: %+v", err)
+	}
+	defer sc.Close()
+
+	for sc.Next() {
+		err := sc.Scan()
+		if err != nil {
+			t.Fatalf("could not scan entry %d: %+v", sc.Entry(), err)
+		}
+
+		// all events hold exactly one Z -> ll decay.
+		if len(pt) == 0 {
+			if len(ele) != 2 {
+				t.Fatalf("entry %d: invalid number of leptons", sc.Entry())
+			}
+			continue
+		}
+		if len(pt) != 2 || len(ele) != 0 {
+			t.Fatalf("entry %d: invalid number of leptons", sc.Entry())
+		}
+		if charge[0] != -charge[1] {
+			t.Fatalf("entry %d: invalid charges %v", sc.Entry(), charge)
+		}
+		if pt[0] < pt[1] {
+			t.Fatalf("entry %d: muons not sorted by pT", sc.Entry())
+		}
+		p1 := fmom.NewPtEtaPhiM(float64(pt[0]), float64(eta[0]), float64(phi[0]), float64(mass[0]))
+		p2 := fmom.NewPtEtaPhiM(float64(pt[1]), float64(eta[1]), float64(phi[1]), float64(mass[1]))
+		if m := fmom.InvMass(&p1, &p2); m < 0.5*cfg.Z.Mass-1e-3 || 2*cfg.Z.Mass+1e-3 < m {
+			t.Fatalf("entry %d: invalid di-muon mass %v", sc.Entry(), m)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		t.Fatalf("could not scan tree: %+v", err)
+	}
+}
+
+func TestInvalidTopMass(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nanogen-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := nanogen.Default()
+	cfg.Top.Mass = 80
+
+	fname := filepath.Join(dir, "events.root")
+	err = nanogen.Generate(fname, 10, cfg)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if _, err := os.Stat(fname); !os.IsNotExist(err) {
+		t.Fatalf("partial output file was not removed: %v", err)
+	}
+}
+
+func TestGenerateSmall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nanogen-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// 128, 150 and 200 events make baskets that groot can not compress:
+	// compressed files of these sizes are written again without compression.
+	var fallbacks int
+	for _, tc := range []struct {
+		nevts    int64
+		compress bool
+	}{
+		{0, true}, {1, true}, {128, true}, {150, true}, {200, true},
+		{999, true}, {1000, true}, {1001, true}, {200, false},
+	} {
+		var (
+			nevts = tc.nevts
+			fname = filepath.Join(dir, fmt.Sprintf("events-%d-%v.root", nevts, tc.compress))
+			opts  []riofs.FileOption
+		)
+		if !tc.compress {
+			opts = append(opts, riofs.WithoutCompression())
+		}
+		err := nanogen.Generate(fname, nevts, nanogen.Default(), opts...)
+		if err != nil {
+			t.Fatalf("could not generate %d events (compress=%v): %+v", nevts, tc.compress, err)
+		}
+
+		f, err := groot.Open(fname)
+		if err != nil {
+			t.Fatalf("could not open file with %d events: %+v", nevts, err)
+		}
+
+		o, err := f.Get("Events")
+		if err != nil {
+			t.Fatalf("could not retrieve tree with %d events: %+v", nevts, err)
+		}
+		tree := o.(rtree.Tree)
+		if got := tree.Entries(); got != nevts {
+			t.Fatalf("invalid number of entries: got=%d, want=%d", got, nevts)
+		}
+
+		var phi float32
+		sc, err := rtree.NewScannerVars(tree, rtree.ScanVar{Name: "MET_phi", Value: &phi})
+		if err != nil {
+			t.Fatalf("could not create scanner: %+v", err)
+		}
+		for sc.Next() {
+			err := sc.Scan()
+			if err != nil {
+				t.Fatalf("could not scan entry %d of %d: %+v", sc.Entry(), nevts, err)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			t.Fatalf("could not scan tree with %d events: %+v", nevts, err)
+		}
+		sc.Close()
+
+		switch compressed := f.Compression() != 0; {
+		case compressed && !tc.compress:
+			t.Fatalf("file with %d events is compressed", nevts)
+		case !compressed && tc.compress && nevts > 1000:
+			t.Fatalf("file with %d events is not compressed", nevts)
+		case !compressed && tc.compress:
+			fallbacks++
+		}
+		f.Close()
+	}
+
+	if fallbacks == 0 {
+		t.Fatalf("no small file generated again without compression")
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rhist"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/root"
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgtex"
+)
+
+// plotFormats are the supported image formats of the plots.
+var plotFormats = []string{"eps", "jpg", "pdf", "png", "svg", "tex", "tif"}
+
+// output describes where the results of the benchmarks are saved.
+// A nil output saves PNG plots in the current directory.
+type output struct {
+	dir     string   // directory where plots are saved
+	formats []string // image formats of the plots
+	root    string   // ROOT file where histograms are saved, if any
+
+	mu    sync.Mutex
+	names []string                     // names of the analyses, in order of completion
+	hists map[string][]hbook.Histogram // histograms of the last run of each analysis
+}
+
+// newOutput returns a new output saving plots in dir, with the provided
+// comma-separated list of image formats, and histograms in the named ROOT
+// file, if any.
+func newOutput(dir, formats, root string) (*output, error) {
+	out := &output{
+		dir:   dir,
+		root:  root,
+		hists: make(map[string][]hbook.Histogram),
+	}
+
+	for _, v := range strings.Split(formats, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+		if !validFormat(v) {
+			return nil, fmt.Errorf(
+				"invalid plot format %q (supported formats: %s)",
+				v, strings.Join(plotFormats, ","),
+			)
+		}
+		out.formats = append(out.formats, v)
+	}
+	if len(out.formats) == 0 {
+		return nil, fmt.Errorf("no plot format in %q", formats)
+	}
+
+	return out, nil
+}
+
+func validFormat(format string) bool {
+	for _, v := range plotFormats {
+		if v == format {
+			return true
+		}
+	}
+	return false
+}
+
+// plot saves the plot of the benchmark in every requested format.
+// The plot of a partial run is saved as NN-xxx.partial.<format>, and does
+// not replace the plot of a complete run.
+func (out *output) plot(bench *Bench, p Plotter, partial bool) error {
+	var (
+		dir     = "."
+		formats = []string{"png"}
+	)
+	if out != nil {
+		dir = out.dir
+		formats = out.formats
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("could not create output directory: %w", err)
+	}
+
+	name := bench.Name
+	if partial {
+		name += ".partial"
+	}
+
+	for _, format := range formats {
+		fname := filepath.Join(dir, name+"."+format)
+		err := savePlot(p, 10*vg.Centimeter, fname)
+		if err != nil {
+			return fmt.Errorf("could not save plot %q: %w", fname, err)
+		}
+	}
+
+	return nil
+}
+
+// cutflow saves the cut-flow of the benchmark as a text table.
+func (out *output) cutflow(bench *Bench, cf *CutFlow) error {
+	if out == nil {
+		return nil
+	}
+
+	fname := filepath.Join(out.dir, bench.Name+".cutflow.txt")
+	f, err := os.Create(fname)
+	if err != nil {
+		return fmt.Errorf("could not create cut-flow file: %w", err)
+	}
+	defer f.Close()
+
+	err = cf.writeTable(f)
+	if err != nil {
+		return fmt.Errorf("could not write cut-flow %q: %w", fname, err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("could not close cut-flow file: %w", err)
+	}
+
+	return nil
+}
+
+// savePlot saves the plot to the named file, with the provided width.
+// The image format is chosen from the file extension.
+func savePlot(p Plotter, w vg.Length, fname string) error {
+	if filepath.Ext(fname) != ".tex" {
+		return p.Save(w, -1, fname)
+	}
+
+	// TeX documents are not supported by Plotter.Save.
+	c := vgtex.NewDocument(w, w/math.Phi)
+	p.Draw(draw.New(c))
+
+	f, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = c.WriteTo(f)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// add records the histograms filled by the benchmark, replacing the ones
+// of a previous run of the same benchmark.
+// The histograms of a partial run carry the mark added by markPartial
+// in their title, which is saved with them.
+func (out *output) add(bench *Bench, hs []hbook.Histogram) {
+	if out == nil || out.root == "" {
+		return
+	}
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+
+	if _, dup := out.hists[bench.Name]; !dup {
+		out.names = append(out.names, bench.Name)
+	}
+	out.hists[bench.Name] = hs
+}
+
+// save writes the recorded histograms to the output ROOT file, under a
+// directory per analysis.
+func (out *output) save() error {
+	if out == nil || out.root == "" {
+		return nil
+	}
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+
+	err := os.MkdirAll(filepath.Dir(out.root), 0755)
+	if err != nil {
+		return fmt.Errorf("could not create output directory: %w", err)
+	}
+
+	f, err := groot.Create(out.root)
+	if err != nil {
+		return fmt.Errorf("could not create ROOT file: %w", err)
+	}
+	defer f.Close()
+
+	for _, name := range out.names {
+		dir, err := riofs.Dir(f).Mkdir(name)
+		if err != nil {
+			return fmt.Errorf("could not create directory %q: %w", name, err)
+		}
+
+		for _, h := range out.hists[name] {
+			var obj root.Object
+			switch h := h.(type) {
+			case *hbook.H1D:
+				obj = rhist.NewH1DFrom(h)
+			case *hbook.H2D:
+				obj = rhist.NewH2DFrom(h)
+			default:
+				return fmt.Errorf("%s: unknown histogram type %T", name, h)
+			}
+
+			err := dir.Put(h.Name(), obj)
+			if err != nil {
+				return fmt.Errorf("could not save histogram %s/%s: %w", name, h.Name(), err)
+			}
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("could not close ROOT file: %w", err)
+	}
+
+	return nil
+}
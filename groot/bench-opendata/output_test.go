@@ -0,0 +1,139 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rhist"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hbook/rootcnv"
+)
+
+func TestOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "events.root")
+	err = createTestFile(fname, 1000)
+	if err != nil {
+		t.Fatalf("could not create test file: %+v", err)
+	}
+
+	_, err = newOutput(dir, "png,gif", "")
+	if err == nil {
+		t.Fatalf("expected an error for an invalid plot format")
+	}
+
+	var (
+		odir  = filepath.Join(dir, "out", "plots")
+		oroot = filepath.Join(dir, "out", "hists.root")
+	)
+	out, err := newOutput(odir, "png, pdf,svg,tex", oroot)
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	var (
+		cfg   = config{fname: fname, out: out}
+		names = []string{"01-basic", "06-struct", "04-arrow"}
+		want  = make(map[string][]hbook.Histogram)
+	)
+	for _, name := range names {
+		hs, _, _, err := run(context.Background(), benchIDs[name], cfg)
+		if err != nil {
+			t.Fatalf("could not run %q: %+v", name, err)
+		}
+		want[name] = hs
+
+		for _, ext := range []string{"png", "pdf", "svg", "tex"} {
+			_, err := os.Stat(filepath.Join(odir, name+"."+ext))
+			if err != nil {
+				t.Fatalf("could not find %s plot of %q: %+v", ext, name, err)
+			}
+		}
+
+		_, err = os.Stat(filepath.Join(odir, name+".cutflow.txt"))
+		if err != nil {
+			t.Fatalf("could not find cut-flow of %q: %+v", name, err)
+		}
+	}
+
+	err = out.save()
+	if err != nil {
+		t.Fatalf("could not save histograms: %+v", err)
+	}
+
+	f, err := groot.Open(oroot)
+	if err != nil {
+		t.Fatalf("could not open ROOT file: %+v", err)
+	}
+	defer f.Close()
+
+	if got, want := len(f.Keys()), len(names); got != want {
+		t.Fatalf("invalid number of directories: got=%d, want=%d", got, want)
+	}
+
+	for _, name := range names {
+		for _, h := range want[name] {
+			o, err := riofs.Dir(f).Get(name + "/" + h.Name())
+			if err != nil {
+				t.Fatalf("could not retrieve %s/%s: %+v", name, h.Name(), err)
+			}
+
+			rh, ok := o.(rhist.H1)
+			if !ok {
+				t.Fatalf("%s/%s: invalid type %T", name, h.Name(), o)
+			}
+
+			err = cmpROOTH1D(rh, h.(*hbook.H1D))
+			if err != nil {
+				t.Fatalf("%s/%s: invalid histogram: %+v", name, h.Name(), err)
+			}
+		}
+	}
+}
+
+// cmpROOTH1D checks that the ROOT histogram holds the same entries and
+// bin contents as the hbook one.
+// ROOT histograms only store the sum of weights (and of squared weights)
+// of each bin, not their moments.
+func cmpROOTH1D(rh rhist.H1, h *hbook.H1D) error {
+	got, err := rootcnv.H1D(rh)
+	if err != nil {
+		return fmt.Errorf("could not convert histogram: %w", err)
+	}
+
+	if got, want := got.Entries(), h.Entries(); got != want {
+		return fmt.Errorf("invalid number of entries: got=%d, want=%d", got, want)
+	}
+	if got, want := got.SumW(), h.SumW(); !approxEqual(got, want, 1e-6) {
+		return fmt.Errorf("invalid sum of weights: got=%v, want=%v", got, want)
+	}
+	if got, want := got.Len(), h.Len(); got != want {
+		return fmt.Errorf("invalid number of bins: got=%d, want=%d", got, want)
+	}
+	for i := 0; i < h.Len(); i++ {
+		var (
+			g = got.Binning.Bins[i].Dist.Dist
+			w = h.Binning.Bins[i].Dist.Dist
+		)
+		if !approxEqual(g.SumW, w.SumW, 1e-6) || !approxEqual(g.SumW2, w.SumW2, 1e-6) {
+			return fmt.Errorf("invalid bin %d: got=%+v, want=%+v", i, g, w)
+		}
+	}
+
+	return nil
+}
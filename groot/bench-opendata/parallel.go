@@ -0,0 +1,120 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// runParallel feeds the entries of the Events trees of the input files
+// selected by the run context to the provided tasks, over nworkers
+// concurrent entry ranges.
+// Each worker opens its own file, fills its own copies of the histograms
+// and cut-flows, and those of all workers are merged into the tasks in
+// entry range order.
+// When the context is canceled, the partial results of all workers are
+// merged and the error of the context is returned.
+func runParallel(ctx context.Context, tasks []*task, fname string, nentries int64, rctx RunContext, nworkers int) error {
+	var (
+		benchs  = make([]*Bench, len(tasks))
+		rctxs   = rctx.split(nentries, nworkers)
+		workers = make([][]*task, len(rctxs))
+		errs    = make([]error, len(rctxs))
+		wg      sync.WaitGroup
+	)
+	for i, t := range tasks {
+		benchs[i] = t.bench
+	}
+
+	wg.Add(len(rctxs))
+	for i, rctx := range rctxs {
+		go func(i int, rctx RunContext) {
+			defer wg.Done()
+			workers[i] = newTasks(benchs)
+			errs[i] = runRange(ctx, workers[i], fname, rctx)
+		}(i, rctx)
+	}
+	wg.Wait()
+
+	var interrupt error
+	for i, err := range errs {
+		switch {
+		case err == nil:
+		case interrupted(err):
+			interrupt = err
+		default:
+			return fmt.Errorf(
+				"could not process entries [%d, %d): %w",
+				rctxs[i].Start, rctxs[i].End(nentries), err,
+			)
+		}
+	}
+
+	for _, ws := range workers {
+		for i, w := range ws {
+			t := tasks[i]
+			if t.err == nil {
+				t.err = w.err
+			}
+			t.done = t.done || w.done
+			t.delta += w.delta
+			t.nevts += w.nevts
+			err := mergeHists(t.hs, w.hs)
+			if err != nil {
+				return fmt.Errorf("could not merge histograms of %q: %w", t.bench.Name, err)
+			}
+			err = t.ana.CutFlow().merge(w.ana.CutFlow())
+			if err != nil {
+				return fmt.Errorf("could not merge cut-flow of %q: %w", t.bench.Name, err)
+			}
+		}
+	}
+
+	return interrupt
+}
+
+// runRange feeds the entries of the Events trees of the input files selected
+// by the run context to the provided tasks.
+func runRange(ctx context.Context, tasks []*task, fname string, rctx RunContext) error {
+	ch, err := openTree(fname)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	return scanTasks(ctx, tasks, ch, rctx)
+}
+
+// splitRange splits [beg, end) into at most n contiguous ranges of
+// (almost) equal sizes.
+func splitRange(beg, end int64, n int) [][2]int64 {
+	size := end - beg
+	if n < 1 {
+		n = 1
+	}
+	if int64(n) > size {
+		n = int(size)
+	}
+	if n < 1 {
+		return [][2]int64{{beg, end}}
+	}
+
+	var (
+		ranges = make([][2]int64, n)
+		chunk  = size / int64(n)
+		rem    = size % int64(n)
+	)
+	for i := range ranges {
+		sz := chunk
+		if int64(i) < rem {
+			sz++
+		}
+		ranges[i] = [2]int64{beg, beg + sz}
+		beg += sz
+	}
+	return ranges
+}
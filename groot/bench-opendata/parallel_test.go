@@ -0,0 +1,101 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/go-hep/examples/groot/bench-opendata/nanogen"
+	"go-hep.org/x/hep/hbook"
+)
+
+func TestSplitRange(t *testing.T) {
+	for _, tc := range []struct {
+		beg, end int64
+		n        int
+		want     [][2]int64
+	}{
+		{0, 10, 1, [][2]int64{{0, 10}}},
+		{0, 10, 2, [][2]int64{{0, 5}, {5, 10}}},
+		{0, 10, 3, [][2]int64{{0, 4}, {4, 7}, {7, 10}}},
+		{0, 3, 5, [][2]int64{{0, 1}, {1, 2}, {2, 3}}},
+		{2, 5, 0, [][2]int64{{2, 5}}},
+		{0, 0, 4, [][2]int64{{0, 0}}},
+	} {
+		t.Run(fmt.Sprintf("%d-%d-%d", tc.beg, tc.end, tc.n), func(t *testing.T) {
+			got := splitRange(tc.beg, tc.end, tc.n)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("invalid ranges:\ngot= %v\nwant=%v", got, tc.want)
+			}
+		})
+	}
+}
+
+// cmpHists checks that the got and want histograms have the same content,
+// bin by bin.
+// Entries and weights must be identical, while weighted moments (which are
+// sums of floating point numbers) may differ up to the relative tolerance tol.
+func cmpHists(got, want []hbook.Histogram, tol float64) error {
+	if len(got) != len(want) {
+		return fmt.Errorf("invalid number of histograms: got=%d, want=%d", len(got), len(want))
+	}
+
+	for i := range want {
+		if got, want := got[i].Name(), want[i].Name(); got != want {
+			return fmt.Errorf("invalid histogram name: got=%q, want=%q", got, want)
+		}
+		var (
+			g = got[i].(*hbook.H1D).Binning
+			w = want[i].(*hbook.H1D).Binning
+		)
+		err := cmpDist1D(g.Dist, w.Dist, tol)
+		if err != nil {
+			return fmt.Errorf("%s: invalid distribution: %w", want[i].Name(), err)
+		}
+		for j := range w.Outflows {
+			err := cmpDist1D(g.Outflows[j], w.Outflows[j], tol)
+			if err != nil {
+				return fmt.Errorf("%s: invalid outflow %d: %w", want[i].Name(), j, err)
+			}
+		}
+		for j := range w.Bins {
+			err := cmpDist1D(g.Bins[j].Dist, w.Bins[j].Dist, tol)
+			if err != nil {
+				return fmt.Errorf("%s: invalid bin %d: %w", want[i].Name(), j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func cmpDist1D(got, want hbook.Dist1D, tol float64) error {
+	if got.Dist != want.Dist {
+		return fmt.Errorf("got=%+v, want=%+v", got.Dist, want.Dist)
+	}
+
+	if !approxEqual(got.Stats.SumWX, want.Stats.SumWX, tol) ||
+		!approxEqual(got.Stats.SumWX2, want.Stats.SumWX2, tol) {
+		return fmt.Errorf("got=%+v, want=%+v", got.Stats, want.Stats)
+	}
+
+	return nil
+}
+
+func approxEqual(a, b, tol float64) bool {
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= tol*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// createTestFile creates a small ROOT file with an Events tree holding
+// generated NanoAOD-like events.
+func createTestFile(fname string, nevts int) error {
+	return nanogen.Generate(fname, int64(nevts), nanogen.Default())
+}
@@ -0,0 +1,405 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/fmom"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+)
+
+func TestTrijetPt(t *testing.T) {
+	// 3 jets whose vector-sum pT (sqrt((30-24)^2 + 20^2) GeV) differs from
+	// the pT of each of them.
+	evt := map[string]interface{}{
+		"Jet_pt":   []float32{30, 24, 20},
+		"Jet_eta":  []float32{0.5, -0.5, 1},
+		"Jet_phi":  []float32{0, math.Pi, math.Pi / 2},
+		"Jet_mass": []float32{10, 10, 10},
+		"Jet_btag": []float32{0.2, 0.9, 0.4},
+	}
+	want := math.Hypot(30-24, 20)
+
+	for _, name := range []string{"06-arrow", "06-basic", "06-rsql", "06-struct"} {
+		t.Run(name, func(t *testing.T) {
+			hs := processAnyEvent(t, benchIDs[name], benchIDs[name].New(), evt)
+			h1 := hs[0].(*hbook.H1D)
+			if n := h1.Entries(); n != 1 {
+				t.Fatalf("invalid number of trijet pT entries: got=%d, want=1", n)
+			}
+			if got := h1.XMean(); math.Abs(got-want) > 1e-4 {
+				t.Fatalf("invalid trijet pT: got=%v, want=%v", got, want)
+			}
+			h2 := hs[1].(*hbook.H1D)
+			if got, want := h2.XMean(), 0.9; h2.Entries() != 1 || math.Abs(got-want) > 1e-6 {
+				t.Fatalf("invalid trijet leading b-tag: got=%v (entries=%d), want=%v", got, h2.Entries(), want)
+			}
+		})
+	}
+}
+
+func TestTransverseMass(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		lep    fmom.PtEtaPhiM
+		metPt  float32
+		metPhi float32
+		want   float64
+	}{
+		{
+			name:   "collinear",
+			lep:    fmom.NewPtEtaPhiM(40, 1.2, 0.5, 0.105),
+			metPt:  30,
+			metPhi: 0.5,
+			want:   0,
+		},
+		{
+			name:   "back-to-back",
+			lep:    fmom.NewPtEtaPhiM(40, -0.3, 1, 0),
+			metPt:  40,
+			metPhi: 1 - math.Pi,
+			want:   80,
+		},
+		{
+			name:   "perpendicular",
+			lep:    fmom.NewPtEtaPhiM(50, 2.1, 3, 0.000511),
+			metPt:  25,
+			metPhi: 3 - math.Pi/2,
+			want:   50,
+		},
+		{
+			name:   "phi-wrap",
+			lep:    fmom.NewPtEtaPhiM(20, 0, 3, 0),
+			metPt:  20,
+			metPhi: -3,
+			want:   2 * 20 * math.Sin((2*math.Pi-6)/2),
+		},
+		{
+			name: "no-met",
+			lep:  fmom.NewPtEtaPhiM(20, 0, 3, 0),
+			want: 0,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := transverseMass(tc.lep, tc.metPt, tc.metPhi)
+			if math.Abs(got-tc.want) > 1e-5 {
+				t.Fatalf("invalid transverse mass: got=%v, want=%v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelectionSpecs(t *testing.T) {
+	// the selections of the ADL benchmarks.
+	if want := (selection4{NJets: 2, JetPt: 40}); spec4 != want {
+		t.Fatalf("invalid 04 selection:\ngot= %+v\nwant=%+v", spec4, want)
+	}
+	if want := (selection5{NMuons: 2, MassLo: 60, MassHi: 120}); spec5 != want {
+		t.Fatalf("invalid 05 selection:\ngot= %+v\nwant=%+v", spec5, want)
+	}
+
+	for _, tc := range []struct {
+		ana  string
+		spec fmt.Stringer
+	}{
+		{ana: "04", spec: spec4},
+		{ana: "05", spec: spec5},
+	} {
+		for _, flavour := range []string{"arrow", "basic", "rsql", "struct"} {
+			name := tc.ana + "-" + flavour
+			if doc := benchIDs[name].Doc; !strings.Contains(doc, tc.spec.String()) {
+				t.Fatalf("%s: description %q does not describe the selection %q", name, doc, tc.spec)
+			}
+		}
+	}
+}
+
+func TestSelection4(t *testing.T) {
+	pt := spec4.JetPt
+	for _, tc := range []struct {
+		name string
+		pt   []float32
+		eta  []float32
+		pass bool
+	}{
+		{name: "no-jet", pass: false},
+		{name: "1-jet", pt: []float32{pt + 50}, eta: []float32{0}, pass: false},
+		{name: "1-hard-jet", pt: []float32{pt + 1, pt - 1}, eta: []float32{0, 0}, pass: false},
+		{name: "threshold", pt: []float32{pt, pt + 10}, eta: []float32{0, 0}, pass: false},
+		{name: "2-hard-jets", pt: []float32{pt + 1, pt + 10}, eta: []float32{0.2, -0.5}, pass: true},
+		{name: "forward-jets", pt: []float32{pt + 1, pt + 10}, eta: []float32{2.5, -3}, pass: true},
+		{name: "3-jets", pt: []float32{pt - 10, pt + 1, pt + 10}, eta: []float32{0, 1, 2}, pass: true},
+	} {
+		evt := map[string]interface{}{
+			"Jet_pt":  tc.pt,
+			"Jet_eta": tc.eta,
+			"MET_pt":  float32(100),
+		}
+		for _, name := range []string{"04-arrow", "04-basic", "04-rsql", "04-struct"} {
+			t.Run(name+"-"+tc.name, func(t *testing.T) {
+				checkSelection(t, benchIDs[name], evt, tc.pass)
+			})
+		}
+	}
+}
+
+func TestSelection5(t *testing.T) {
+	var (
+		lo  = spec5.MassLo
+		hi  = spec5.MassHi
+		mid = 0.5 * (lo + hi)
+	)
+
+	// muons returns an event with a pair of back-to-back massless muons at
+	// eta=0, with the provided invariant mass and charges, and additional
+	// muons of the provided pT.
+	muons := func(mass float64, q1, q2 int32, pts ...float32) map[string]interface{} {
+		evt := map[string]interface{}{
+			"Muon_pt":     []float32{float32(mass / 2), float32(mass / 2)},
+			"Muon_eta":    []float32{0, 0},
+			"Muon_phi":    []float32{0, math.Pi},
+			"Muon_mass":   []float32{0, 0},
+			"Muon_charge": []int32{q1, q2},
+			"MET_pt":      float32(100),
+		}
+		for _, pt := range pts {
+			evt["Muon_pt"] = append(evt["Muon_pt"].([]float32), pt)
+			evt["Muon_eta"] = append(evt["Muon_eta"].([]float32), 1)
+			evt["Muon_phi"] = append(evt["Muon_phi"].([]float32), 1)
+			evt["Muon_mass"] = append(evt["Muon_mass"].([]float32), 0.105)
+			evt["Muon_charge"] = append(evt["Muon_charge"].([]int32), q1)
+		}
+		return evt
+	}
+
+	for _, tc := range []struct {
+		name string
+		evt  map[string]interface{}
+		pass bool
+	}{
+		{name: "no-muon", evt: map[string]interface{}{}, pass: false},
+		{name: "1-muon", evt: map[string]interface{}{"Muon_pt": []float32{50}, "Muon_charge": []int32{1}}, pass: false},
+		{name: "in-window", evt: muons(mid, -1, +1), pass: true},
+		{name: "low-edge", evt: muons(lo+1, +1, -1), pass: true},
+		{name: "high-edge", evt: muons(hi-1, +1, -1), pass: true},
+		{name: "below", evt: muons(lo-1, -1, +1), pass: false},
+		{name: "above", evt: muons(hi+1, -1, +1), pass: false},
+		{name: "same-sign", evt: muons(mid, -1, -1), pass: false},
+		{name: "3-muons", evt: muons(mid, -1, +1, 20), pass: true},
+	} {
+		for _, name := range []string{"05-arrow", "05-basic", "05-rsql", "05-struct"} {
+			t.Run(name+"-"+tc.name, func(t *testing.T) {
+				checkSelection(t, benchIDs[name], tc.evt, tc.pass)
+			})
+		}
+	}
+}
+
+// allocEvent is an event with jets, muons and electrons, some of them
+// overlapping, to check the allocations of the per-event processing.
+var allocEvent = map[string]interface{}{
+	"Jet_pt":       []float32{80, 45, 35, 20},
+	"Jet_eta":      []float32{0.5, -0.5, 1.5, 0.2},
+	"Jet_phi":      []float32{0, 2, -2, 1},
+	"Muon_pt":      []float32{25, 5},
+	"Muon_eta":     []float32{0.5, 1},
+	"Muon_phi":     []float32{0.1, 3},
+	"Electron_pt":  []float32{15},
+	"Electron_eta": []float32{-0.4},
+	"Electron_phi": []float32{2.1},
+	"MET_pt":       float32(300),
+}
+
+func TestBasicAllocs(t *testing.T) {
+	for _, name := range []string{"03-basic", "04-basic", "07-basic"} {
+		t.Run(name, func(t *testing.T) {
+			ana := benchIDs[name].New()
+			processEvent(t, ana, allocEvent)
+			if n := testing.AllocsPerRun(100, func() { _ = ana.Process() }); n != 0 {
+				t.Fatalf("invalid number of allocations per event: got=%v, want=0", n)
+			}
+		})
+	}
+}
+
+func BenchmarkProcess(b *testing.B) {
+	for _, name := range []string{
+		"03-basic", "03-struct",
+		"04-basic", "04-struct",
+		"07-basic", "07-struct",
+	} {
+		b.Run(name, func(b *testing.B) {
+			ana := benchIDs[name].New()
+			ana.Book()
+			for _, v := range ana.Vars() {
+				ptr := reflect.ValueOf(v.Value).Elem()
+				if val, ok := allocEvent[v.Name]; ok {
+					ptr.Set(reflect.ValueOf(val))
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = ana.Process()
+			}
+		})
+	}
+}
+
+// checkSelection processes the event with the analysis and checks whether
+// it passed the whole selection, i.e. the last cut of its cut-flow, and
+// was histogrammed.
+func checkSelection(t *testing.T, bench *Bench, evt map[string]interface{}, pass bool) {
+	t.Helper()
+
+	ana := bench.New()
+	hs := processAnyEvent(t, bench, ana, evt)
+	want := int64(0)
+	if pass {
+		want = 1
+	}
+	cuts := ana.CutFlow().Cuts
+	if got := cuts[len(cuts)-1].N; got != want {
+		t.Fatalf("invalid selection: got=%d events passing %q, want=%d", got, cuts[len(cuts)-1].Name, want)
+	}
+	if got := hs[0].Entries(); got != want {
+		t.Fatalf("invalid number of histogrammed events: got=%d, want=%d", got, want)
+	}
+}
+
+// processAnyEvent processes the provided event with the analysis of the
+// bench, event by event or, for tree analyses, through a 1-entry tree
+// (see processTreeEvent), and returns the filled histograms.
+func processAnyEvent(t *testing.T, bench *Bench, ana Analysis, evt map[string]interface{}) []hbook.Histogram {
+	t.Helper()
+
+	if ana, ok := ana.(TreeAnalysis); ok {
+		return processTreeEvent(t, bench, ana, evt)
+	}
+	return processEvent(t, ana, evt)
+}
+
+// processEvent books the histograms of the analysis, processes the
+// provided event, given as the values of its branches, and returns the
+// filled histograms.
+// Branches read by the analysis and missing from the event are empty.
+func processEvent(t *testing.T, ana Analysis, evt map[string]interface{}) []hbook.Histogram {
+	t.Helper()
+
+	hs := ana.Book()
+	for _, v := range ana.Vars() {
+		ptr := reflect.ValueOf(v.Value).Elem()
+		val, ok := evt[v.Name]
+		if !ok {
+			ptr.Set(reflect.Zero(ptr.Type()))
+			continue
+		}
+		ptr.Set(reflect.ValueOf(val))
+	}
+	err := ana.Process()
+	if err != nil {
+		t.Fatalf("could not process event: %+v", err)
+	}
+	return hs
+}
+
+// processTreeEvent writes the provided event, given as the values of its
+// branches, to a 1-entry Events tree and returns the histograms filled by
+// the tree analysis.
+// Slices are written with the count branch of their collection,
+// e.g. nJet for Jet_pt.
+// Branches read by the analysis and missing from the event are zero, with
+// the length of the other slices of their collection.
+func processTreeEvent(t *testing.T, bench *Bench, ana TreeAnalysis, evt map[string]interface{}) []hbook.Histogram {
+	t.Helper()
+
+	lens := make(map[string]int)
+	for name, val := range evt {
+		if v := reflect.ValueOf(val); v.Kind() == reflect.Slice {
+			lens[collection(name)] = v.Len()
+		}
+	}
+	full := make(map[string]interface{}, len(evt))
+	for name, val := range evt {
+		full[name] = val
+	}
+	for _, v := range ana.Vars() {
+		if _, ok := full[v.Name]; ok {
+			continue
+		}
+		rt := reflect.TypeOf(v.Value).Elem()
+		if rt.Kind() != reflect.Slice {
+			full[v.Name] = reflect.Zero(rt).Interface()
+			continue
+		}
+		n := lens[collection(v.Name)]
+		full[v.Name] = reflect.MakeSlice(rt, n, n).Interface()
+	}
+	evt = full
+
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		names  = make([]string, 0, len(evt))
+		vars   []rtree.WriteVar
+		counts = make(map[string]bool)
+	)
+	for name := range evt {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := reflect.ValueOf(evt[name])
+		if v.Kind() != reflect.Slice {
+			ptr := reflect.New(v.Type())
+			ptr.Elem().Set(v)
+			vars = append(vars, rtree.WriteVar{Name: name, Value: ptr.Interface()})
+			continue
+		}
+		count := "n" + collection(name)
+		if !counts[count] {
+			counts[count] = true
+			n := int32(v.Len())
+			vars = append(vars, rtree.WriteVar{Name: count, Value: &n})
+		}
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		vars = append(vars, rtree.WriteVar{Name: name, Value: ptr.Interface(), Count: count})
+	}
+
+	fname := filepath.Join(dir, "events.root")
+	err = createTree(fname, vars)
+	if err != nil {
+		t.Fatalf("could not create input file: %+v", err)
+	}
+
+	hs := ana.Book()
+	err = processTree(context.Background(), &task{bench: bench, ana: ana, hs: hs}, config{fname: fname})
+	if err != nil {
+		t.Fatalf("could not process event: %+v", err)
+	}
+	return hs
+}
+
+// collection returns the name of the collection of a branch,
+// e.g. Jet for Jet_pt.
+func collection(name string) string {
+	return name[:strings.Index(name, "_")]
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-hep/examples/groot/progress"
+)
+
+// TestProgress checks that all the flavours of the analyses report all the
+// entries they process.
+func TestProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	for _, rctx := range []RunContext{
+		{},
+		{Start: 100, N: 500, Stride: 3},
+	} {
+		for _, name := range []string{"01-basic", "01-arrow", "01-rsql", "05-rsql", "05-struct"} {
+			for _, nworkers := range []int{1, 3} {
+				rctx := rctx
+				name := name
+				nworkers := nworkers
+				t.Run(fmt.Sprintf("%s-%s-j=%d", name, ctxName(rctx), nworkers), func(t *testing.T) {
+					var (
+						w     = new(strings.Builder)
+						total = rctx.Entries(goldenEvents)
+						rep   = progress.New(w, total, progress.WithInterval(time.Hour))
+						cfg   = config{fname: goldenInput, nworkers: nworkers, rctx: rctx, out: out}
+					)
+					_, _, nevts, err := run(progress.NewContext(context.Background(), rep), benchIDs[name], cfg)
+					if err != nil {
+						t.Fatalf("could not run %q: %+v", name, err)
+					}
+					rep.Close()
+
+					if nevts != total {
+						t.Fatalf("invalid number of processed entries: got=%d, want=%d", nevts, total)
+					}
+					want := fmt.Sprintf("%d/%d entries (100.0%%)", total, total)
+					if got := w.String(); !strings.HasPrefix(got, want) {
+						t.Fatalf("invalid progress:\ngot= %q\nwant=%q", got, want)
+					}
+				})
+			}
+		}
+	}
+}
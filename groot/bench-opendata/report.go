@@ -0,0 +1,249 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-hep.org/x/hep/hbook"
+)
+
+// Report is the machine-readable report of a benchmarks run.
+type Report struct {
+	mu       sync.Mutex
+	Analyses []AnalysisReport `json:"analyses"`
+}
+
+// AnalysisReport describes the run of a single analysis.
+// Repeated runs of an analysis are numbered from 1.
+//
+// With -shared, the wall time is the time spent processing events while
+// the CPU time and bytes read are the ones of the whole shared pass.
+// The peak RSS is the maximum resident set size of the whole process since
+// it started, as reported by getrusage at the end of the analysis: it is not
+// the one of the analysis, and never decreases from one analysis to the next.
+// The I/O report (see IOReport) is only recorded with -io and, with
+// -shared, is also the one of the whole shared pass.
+//
+// Runs interrupted on SIGINT or after the -timeout deadline are marked as
+// partial: their number of events is the number of entries processed
+// before the interruption.
+type AnalysisReport struct {
+	Name           string       `json:"name"`
+	Run            int          `json:"run"`
+	Input          string       `json:"input"`
+	GoVersion      string       `json:"go_version"`
+	HepVersion     string       `json:"hep_version"`
+	WallTime       float64      `json:"wall_time_s"`
+	CPUTime        float64      `json:"cpu_time_s"`
+	Start          int64        `json:"start"`  // index of the first entry to process
+	Stride         int64        `json:"stride"` // distance between two processed entries
+	Events         int64        `json:"events"` // number of processed entries
+	Rate           float64      `json:"events_per_s"`
+	BytesRead      int64        `json:"bytes_read"`
+	ProcessPeakRSS int64        `json:"process_peak_rss_bytes"`
+	Files          []FileReport `json:"files,omitempty"`
+	Partial        bool         `json:"partial,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	Hists          []HistReport `json:"histograms"`
+	CutFlow        []CutReport  `json:"cutflow,omitempty"`
+	IO             *IOReport    `json:"io,omitempty"`
+}
+
+// FileReport describes the entries of an input file.
+type FileReport struct {
+	Name    string `json:"name"`
+	Entries int64  `json:"entries"` // number of entries of the Events tree
+	Events  int64  `json:"events"`  // number of entries selected by the run context
+}
+
+// HistReport summarizes a filled histogram.
+type HistReport struct {
+	Name    string  `json:"name"`
+	SumW    float64 `json:"sumw"`
+	Entries int64   `json:"entries"`
+}
+
+// CutReport describes the events passing a cut of the cut-flow of
+// an analysis.
+type CutReport struct {
+	Name   string  `json:"name"`
+	Events int64   `json:"events"`  // number of events passing the cut
+	SumW   float64 `json:"sumw"`    // sum of weights of the events passing the cut
+	Eff    float64 `json:"eff"`     // efficiency relative to the previous cut
+	CumEff float64 `json:"cum_eff"` // efficiency relative to the first cut
+}
+
+// add adds the report of the run of a benchmark over nevts events,
+// between the beg and end snapshots of the resources used by the process.
+func (rep *Report) add(bench *Bench, cfg config, nevts int64, hs []hbook.Histogram, cf *CutFlow, beg, end usage, err error) {
+	rep.addTimed(bench, cfg, nevts, hs, cf, end.wall.Sub(beg.wall).Seconds(), beg, end, err)
+}
+
+// addTimed is like add, with an explicit wall time, in seconds.
+func (rep *Report) addTimed(bench *Bench, cfg config, nevts int64, hs []hbook.Histogram, cf *CutFlow, wall float64, beg, end usage, err error) {
+	if rep == nil {
+		return
+	}
+
+	ana := AnalysisReport{
+		Name:           bench.Name,
+		Input:          cfg.fname,
+		GoVersion:      runtime.Version(),
+		HepVersion:     hepVersion(),
+		WallTime:       wall,
+		CPUTime:        (end.cpu - beg.cpu).Seconds(),
+		Start:          cfg.rctx.Start,
+		Stride:         cfg.rctx.stride(),
+		Events:         nevts,
+		BytesRead:      end.bytes - beg.bytes,
+		ProcessPeakRSS: end.peakRSS,
+		Hists:          make([]HistReport, len(hs)),
+		IO:             newIOReport(beg, end),
+	}
+	if wall > 0 {
+		ana.Rate = float64(nevts) / wall
+	}
+	switch {
+	case err == nil:
+	case interrupted(err):
+		ana.Partial = true
+	default:
+		ana.Error = err.Error()
+	}
+	off := int64(0)
+	for _, f := range cfg.inputs {
+		ana.Files = append(ana.Files, FileReport{
+			Name:    f.name,
+			Entries: f.entries,
+			Events:  cfg.rctx.local(off, f.entries).Entries(f.entries),
+		})
+		off += f.entries
+	}
+	for i, h := range hs {
+		ana.Hists[i] = HistReport{
+			Name:    h.Name(),
+			SumW:    sumW(h),
+			Entries: int64(h.Entries()),
+		}
+	}
+	if cf != nil {
+		for i, c := range cf.Cuts {
+			ana.CutFlow = append(ana.CutFlow, CutReport{
+				Name:   c.Name,
+				Events: c.N,
+				SumW:   c.SumW,
+				Eff:    cf.Eff(i),
+				CumEff: cf.CumEff(i),
+			})
+		}
+	}
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	for _, v := range rep.Analyses {
+		if v.Name == ana.Name {
+			ana.Run = v.Run
+		}
+	}
+	ana.Run++
+	rep.Analyses = append(rep.Analyses, ana)
+}
+
+// Save writes the report to the named file, as CSV if the file has
+// a ".csv" extension, as JSON otherwise.
+func (rep *Report) Save(fname string) error {
+	f, err := os.Create(fname)
+	if err != nil {
+		return fmt.Errorf("could not create report file: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".csv":
+		err = rep.writeCSV(f)
+	default:
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(rep)
+	}
+	if err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("could not close report file: %w", err)
+	}
+
+	return nil
+}
+
+// writeCSV writes the report as CSV, with one record per histogram.
+func (rep *Report) writeCSV(f *os.File) error {
+	w := csv.NewWriter(f)
+	err := w.Write([]string{
+		"name", "run", "input", "go_version", "hep_version",
+		"wall_time_s", "cpu_time_s", "start", "stride", "events", "events_per_s",
+		"bytes_read", "process_peak_rss_bytes", "partial", "error",
+		"histogram", "sumw", "entries",
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		ftoa = func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+		itoa = func(v int64) string { return strconv.FormatInt(v, 10) }
+	)
+	for _, ana := range rep.Analyses {
+		hs := ana.Hists
+		if len(hs) == 0 {
+			hs = []HistReport{{}}
+		}
+		for _, h := range hs {
+			err := w.Write([]string{
+				ana.Name, strconv.Itoa(ana.Run), ana.Input, ana.GoVersion, ana.HepVersion,
+				ftoa(ana.WallTime), ftoa(ana.CPUTime),
+				itoa(ana.Start), itoa(ana.Stride), itoa(ana.Events), ftoa(ana.Rate),
+				itoa(ana.BytesRead), itoa(ana.ProcessPeakRSS), strconv.FormatBool(ana.Partial), ana.Error,
+				h.Name, ftoa(h.SumW), itoa(h.Entries),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// hepVersion returns the version of the go-hep module bench-opendata
+// was built with.
+func hepVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == "go-hep.org/x/hep" {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			return dep.Version
+		}
+	}
+	return ""
+}
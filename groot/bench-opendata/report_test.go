@@ -0,0 +1,125 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "events.root")
+	err = createTestFile(fname, 100)
+	if err != nil {
+		t.Fatalf("could not create test file: %+v", err)
+	}
+
+	var (
+		rep = new(Report)
+		cfg = config{fname: fname, rctx: RunContext{Start: 10, N: 50, Stride: 2}}
+	)
+	for _, name := range []string{"01-basic", "04-arrow"} {
+		bench := benchIDs[name]
+		beg := newUsage()
+		hs, cf, nevts, err := run(context.Background(), bench, cfg)
+		end := newUsage()
+		os.Remove(bench.Name + ".png")
+		if err != nil {
+			t.Fatalf("could not run %q: %+v", name, err)
+		}
+		rep.add(bench, cfg, nevts, hs, cf, beg, end, err)
+	}
+
+	if got, want := len(rep.Analyses), 2; got != want {
+		t.Fatalf("invalid number of analyses: got=%d, want=%d", got, want)
+	}
+	for _, ana := range rep.Analyses {
+		if got, want := ana.Events, int64(45); got != want {
+			t.Fatalf("%s: invalid number of events: got=%d, want=%d", ana.Name, got, want)
+		}
+		if ana.BytesRead <= 0 {
+			t.Fatalf("%s: invalid number of bytes read: %d", ana.Name, ana.BytesRead)
+		}
+		if len(ana.Hists) == 0 {
+			t.Fatalf("%s: no histograms", ana.Name)
+		}
+		if len(ana.CutFlow) == 0 || ana.CutFlow[0].Events != ana.Events {
+			t.Fatalf("%s: invalid cut-flow: %+v", ana.Name, ana.CutFlow)
+		}
+	}
+	// the peak RSS is the one of the whole process.
+	if beg, end := rep.Analyses[0].ProcessPeakRSS, rep.Analyses[1].ProcessPeakRSS; end < beg {
+		t.Fatalf("process peak RSS decreased: %d -> %d", beg, end)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		oname := filepath.Join(dir, "report.json")
+		err := rep.Save(oname)
+		if err != nil {
+			t.Fatalf("could not save report: %+v", err)
+		}
+
+		raw, err := ioutil.ReadFile(oname)
+		if err != nil {
+			t.Fatalf("could not read report: %+v", err)
+		}
+
+		var got Report
+		err = json.Unmarshal(raw, &got)
+		if err != nil {
+			t.Fatalf("could not decode report: %+v", err)
+		}
+
+		if got, want := len(got.Analyses), len(rep.Analyses); got != want {
+			t.Fatalf("invalid number of analyses: got=%d, want=%d", got, want)
+		}
+		for i, ana := range got.Analyses {
+			want := rep.Analyses[i]
+			if ana.Name != want.Name || ana.Events != want.Events ||
+				len(ana.Hists) != len(want.Hists) || !reflect.DeepEqual(ana.CutFlow, want.CutFlow) {
+				t.Fatalf("invalid analysis report:\ngot= %+v\nwant=%+v", ana, want)
+			}
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		oname := filepath.Join(dir, "report.csv")
+		err := rep.Save(oname)
+		if err != nil {
+			t.Fatalf("could not save report: %+v", err)
+		}
+
+		f, err := os.Open(oname)
+		if err != nil {
+			t.Fatalf("could not open report: %+v", err)
+		}
+		defer f.Close()
+
+		rows, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			t.Fatalf("could not decode report: %+v", err)
+		}
+
+		want := 1 // header
+		for _, ana := range rep.Analyses {
+			want += len(ana.Hists)
+		}
+		if got := len(rows); got != want {
+			t.Fatalf("invalid number of rows: got=%d, want=%d", got, want)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-hep/examples/groot/progress"
+	"go-hep.org/x/hep/groot/rsql/rsqldrv"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// errStopScan is returned by rsql.Scan hooks to stop scanning a tree.
+var errStopScan = errors.New("bench-opendata: stop scan")
+
+// rsqlAnalysis is an analysis reading the Events tree through rsqldrv.
+//
+// The columns needed by the analysis are retrieved with an SQL query,
+// optionally pre-selecting the entries with a WHERE clause.
+// The WHERE clause holds every cut of the analysis rsqldrv can evaluate:
+// the cuts on object multiplicities, and on their sums.
+//
+// The other selection steps are performed by the Process method of the
+// embedded analysis, used as a post-processing hook, as rsqldrv (v0.24.1)
+// only evaluates comparisons (=, !=, <, <=, >, >=), AND, OR and arithmetic
+// (+, -, *, /) of scalar columns and literals:
+//   - comparisons of jagged columns (e.g. Jet_pt > 30) are rejected, so the
+//     per-object pT, eta and isolation cuts can not be expressed;
+//   - elements of jagged columns can not be indexed (e.g. Muon_charge[0]),
+//     so the charge and flavour of lepton pairs can not be compared;
+//   - functions and aggregates (COUNT, SUM, SQRT, COS, ...) are not
+//     implemented, so the invariant and transverse masses, the number of
+//     objects passing a cut and the sums of their pT can not be computed;
+//   - NOT, BETWEEN, IN, unary minus and % are not implemented either.
+//
+// The documentation of each rsqlN analysis lists the steps performed in SQL
+// and in Go, and TestRSQLDriver checks rsqldrv still rejects the expressions
+// above.
+//
+// Entries rejected by the WHERE clause are never given to the embedded
+// analysis: as the WHERE clause is the first cut after "all events" of the
+// cut-flow of the analysis, or looser, they are recorded as only passing
+// the first ("all events") cut of its cut-flow.
+//
+// When the embedded analysis is a Deriver, the quantities it derives can be
+// written to a friend tree (see OnDerived): the WHERE clause is then
+// dropped, so each row maps to an entry of the tree.
+type rsqlAnalysis struct {
+	Analysis
+
+	where   string       // SQL WHERE clause pre-selecting entries
+	derived func() error // hook called with the quantities derived from each entry, if any
+}
+
+func newRSQL(ana Analysis, where string) *rsqlAnalysis {
+	return &rsqlAnalysis{Analysis: ana, where: where}
+}
+
+// Derived returns the columns derived by the embedded analysis, if it is
+// a Deriver, and no column otherwise.
+func (ana *rsqlAnalysis) Derived() []rtree.WriteVar {
+	d, ok := ana.Analysis.(Deriver)
+	if !ok {
+		return nil
+	}
+	return d.Derived()
+}
+
+// OnDerived registers the function called after each row is processed.
+func (ana *rsqlAnalysis) OnDerived(fn func() error) {
+	ana.derived = fn
+}
+
+// preselects returns whether the entries are pre-selected by the WHERE
+// clause.
+// The WHERE clause is only applied when all the entries are processed and
+// no derived quantity is written: otherwise, rows would not map to the
+// entries selected by the run context, or to the entries of the friend
+// tree.
+func (ana *rsqlAnalysis) preselects(ctx RunContext) bool {
+	return ana.where != "" && ctx.IsAll() && ana.derived == nil
+}
+
+// Query returns the SQL query retrieving the data needed by the analysis.
+func (ana *rsqlAnalysis) Query(tree rtree.Tree, ctx RunContext) string {
+	vars := ana.Analysis.Vars()
+	cols := make([]string, len(vars))
+	for i, v := range vars {
+		cols[i] = v.Name
+	}
+
+	query := fmt.Sprintf("SELECT (%s) FROM %s", strings.Join(cols, ", "), tree.Name())
+	if ana.preselects(ctx) {
+		query += " WHERE " + ana.where
+	}
+	return query
+}
+
+func (ana *rsqlAnalysis) Process() error {
+	return fmt.Errorf("rsql analyses can only process whole trees")
+}
+
+// ProcessTree processes the rows of the query over the entries selected by
+// the run context.
+// The context is checked before each row.
+// When the context is canceled while entries are pre-selected by the WHERE
+// clause, the returned number of entries is the number of pre-selected rows
+// processed so far.
+func (ana *rsqlAnalysis) ProcessTree(ctx context.Context, tree rtree.Tree, rctx RunContext) (int64, error) {
+	vars := ana.Analysis.Vars()
+	args := make([]interface{}, len(vars))
+	for i, v := range vars {
+		args[i] = v.Value
+	}
+
+	db := rsqldrv.OpenDB(rtree.FileOf(tree))
+	defer db.Close()
+
+	rows, err := db.Query(ana.Query(tree, rctx))
+	if err != nil {
+		return 0, fmt.Errorf("could not query tree: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		all   = rctx.IsAll()
+		pre   = ana.preselects(rctx)
+		end   = rctx.End(tree.Entries())
+		stop  = ctx.Done()
+		rep   = progress.FromContext(ctx)
+		n     int64 // number of rows
+		nevts int64 // number of processed entries
+	)
+	for ; rows.Next(); n++ {
+		select {
+		case <-stop:
+			return nevts, ctx.Err()
+		default:
+		}
+
+		if !all {
+			if n >= end {
+				break
+			}
+			if !rctx.Selects(n) {
+				continue
+			}
+		}
+
+		err = rows.Scan(args...)
+		if err != nil {
+			return nevts, fmt.Errorf("could not scan row %d: %w", n, err)
+		}
+
+		err = ana.Analysis.Process()
+		if err != nil {
+			return nevts, fmt.Errorf("could not process row %d: %w", n, err)
+		}
+		if ana.derived != nil {
+			err = ana.derived()
+			if err != nil {
+				return nevts, fmt.Errorf("could not process row %d: %w", n, err)
+			}
+		}
+		nevts++
+		rep.Add(1)
+	}
+
+	err = rows.Err()
+	if err != nil && err != io.EOF {
+		return nevts, fmt.Errorf("could not scan whole tree: %w", err)
+	}
+
+	if pre {
+		ana.CutFlow().fillN(0, tree.Entries()-n, 1)
+		rep.Add(tree.Entries() - n)
+		nevts = tree.Entries()
+	}
+
+	return nevts, nil
+}
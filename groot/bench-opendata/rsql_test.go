@@ -0,0 +1,259 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rsql/rsqldrv"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+)
+
+func runTreeAnalysis(bench *Bench, fname string, ctx RunContext) ([]hbook.Histogram, error) {
+	ana := bench.New().(TreeAnalysis)
+	hs := ana.Book()
+	err := processTree(context.Background(), &task{bench: bench, ana: ana, hs: hs}, config{fname: fname, rctx: ctx})
+	return hs, err
+}
+
+// rsqlSelections describes, for each rsql analysis, the entries its WHERE
+// clause should select, from their object multiplicities.
+var rsqlSelections = map[string]func(njet, nmuon, nele uint32) bool{
+	"02-rsql": func(njet, nmuon, nele uint32) bool { return njet >= 1 },
+	"03-rsql": func(njet, nmuon, nele uint32) bool { return njet >= 1 },
+	"04-rsql": func(njet, nmuon, nele uint32) bool { return int(njet) >= spec4.NJets },
+	"05-rsql": func(njet, nmuon, nele uint32) bool { return int(nmuon) >= spec5.NMuons },
+	"06-rsql": func(njet, nmuon, nele uint32) bool { return njet >= 3 },
+	"07-rsql": func(njet, nmuon, nele uint32) bool { return njet >= 1 },
+	"08-rsql": func(njet, nmuon, nele uint32) bool { return nmuon+nele >= 3 },
+}
+
+func TestRSQLQuery(t *testing.T) {
+	f, err := groot.Open(goldenInput)
+	if err != nil {
+		t.Fatalf("could not open input file: %+v", err)
+	}
+	defer f.Close()
+
+	o, err := f.Get("Events")
+	if err != nil {
+		t.Fatalf("could not retrieve tree: %+v", err)
+	}
+	tree := o.(rtree.Tree)
+
+	for _, name := range sortedBenchNames() {
+		ana, ok := benchIDs[name].New().(*rsqlAnalysis)
+		if !ok {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			sel, ok := rsqlSelections[name]
+			if !ok {
+				t.Fatalf("no expected selection for %q", name)
+			}
+
+			// the WHERE clause is dropped when only some entries are processed.
+			if q := ana.Query(tree, RunContext{N: 10}); strings.Contains(q, " WHERE ") {
+				t.Fatalf("pre-selection of a subset of entries in query %q", q)
+			}
+
+			want, err := rsqlSelectedEntries(tree, strings.Replace(name, "-rsql", "-basic", 1), sel)
+			if err != nil {
+				t.Fatalf("could not select entries: %+v", err)
+			}
+
+			ana.Book()
+			vars := ana.Analysis.Vars()
+			args := make([]interface{}, len(vars))
+			for i, v := range vars {
+				args[i] = v.Value
+			}
+
+			db := rsqldrv.OpenDB(rtree.FileOf(tree))
+			defer db.Close()
+
+			query := ana.Query(tree, RunContext{})
+			rows, err := db.Query(query)
+			if err != nil {
+				t.Fatalf("could not run query %q: %+v", query, err)
+			}
+			defer rows.Close()
+
+			var got []string
+			for rows.Next() {
+				err = rows.Scan(args...)
+				if err != nil {
+					t.Fatalf("could not scan row %d: %+v", len(got), err)
+				}
+				got = append(got, fmtVars(vars))
+			}
+			if err := rows.Err(); err != nil && err != io.EOF {
+				t.Fatalf("could not scan rows: %+v", err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("invalid number of selected entries: got=%d, want=%d (query=%q)", len(got), len(want), query)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("invalid selected entry %d:\ngot= %s\nwant=%s", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+// rsqlSelectedEntries returns the values of the variables of the named
+// analysis for the entries of the tree selected by sel.
+// It checks all the entries passing the first cut after "all events" of
+// the analysis are selected.
+func rsqlSelectedEntries(tree rtree.Tree, name string, sel func(njet, nmuon, nele uint32) bool) ([]string, error) {
+	ana := benchIDs[name].New()
+	ana.Book()
+	vars := ana.Vars()
+
+	// multiplicities are read from the lengths of jagged columns, with the
+	// same scanner as the variables of the analysis: scanners of a tree
+	// share its branches.
+	var (
+		svars = append([]rtree.ScanVar(nil), vars...)
+		pts   = make([]*[]float32, 3)
+	)
+	for i, name := range []string{"Jet_pt", "Muon_pt", "Electron_pt"} {
+		for _, v := range vars {
+			if v.Name == name {
+				pts[i] = v.Value.(*[]float32)
+			}
+		}
+		if pts[i] == nil {
+			pts[i] = new([]float32)
+			svars = append(svars, rtree.ScanVar{Name: name, Value: pts[i]})
+		}
+	}
+
+	sc, err := rtree.NewScannerVars(tree, svars...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create scanner of %q: %w", name, err)
+	}
+	defer sc.Close()
+
+	var (
+		cf   = ana.CutFlow()
+		vals []string
+	)
+	for sc.Next() {
+		err := sc.Scan()
+		if err != nil {
+			return nil, fmt.Errorf("could not scan entry %d: %w", sc.Entry(), err)
+		}
+
+		npass := cf.Cuts[1].N
+		err = ana.Process()
+		if err != nil {
+			return nil, fmt.Errorf("could not process entry %d: %w", sc.Entry(), err)
+		}
+
+		njet, nmuon, nele := uint32(len(*pts[0])), uint32(len(*pts[1])), uint32(len(*pts[2]))
+		switch {
+		case sel(njet, nmuon, nele):
+			vals = append(vals, fmtVars(vars))
+		case cf.Cuts[1].N != npass:
+			return nil, fmt.Errorf("entry %d passes cut %q but is not selected", sc.Entry(), cf.Cuts[1].Name)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not scan tree: %w", err)
+	}
+
+	return vals, nil
+}
+
+// fmtVars formats the values of the variables.
+func fmtVars(vars []rtree.ScanVar) string {
+	vs := make([]string, len(vars))
+	for i, v := range vars {
+		vs[i] = fmt.Sprintf("%s=%v", v.Name, reflect.ValueOf(v.Value).Elem().Interface())
+	}
+	return strings.Join(vs, " ")
+}
+
+// TestRSQLDriver checks rsqldrv still rejects the expressions the rsql
+// analyses can not use (see rsqlAnalysis).
+func TestRSQLDriver(t *testing.T) {
+	f, err := groot.Open(goldenInput)
+	if err != nil {
+		t.Fatalf("could not open input file: %+v", err)
+	}
+	defer f.Close()
+
+	db := rsqldrv.OpenDB(f)
+	defer db.Close()
+
+	for _, tc := range []struct {
+		name  string
+		where string
+	}{
+		{"jagged comparison", "Jet_pt > 30"},
+		{"indexing", "Muon_charge[0] != Muon_charge[1]"},
+		{"aggregate", "SUM(Jet_pt) > 200"},
+		{"function", "SQRT(MET_pt) > 5"},
+		{"not", "NOT nJet >= 1"},
+		{"between", "nJet BETWEEN 1 AND 3"},
+		{"in", "nJet IN (1, 2)"},
+		{"unary minus", "-nJet < 0"},
+		{"modulo", "nJet % 2 = 0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := rsqlCount(db, "SELECT (nJet) FROM Events WHERE "+tc.where)
+			if err == nil {
+				t.Fatalf("rsqldrv accepts WHERE %s: update rsqlAnalysis and the rsql analyses", tc.where)
+			}
+		})
+	}
+
+	// the expressions used by the rsql analyses are supported.
+	for _, where := range []string{"nJet >= 1", "nMuon + nElectron >= 3"} {
+		err := rsqlCount(db, "SELECT (nJet) FROM Events WHERE "+where)
+		if err != nil {
+			t.Fatalf("rsqldrv rejects WHERE %s: %+v", where, err)
+		}
+	}
+}
+
+// rsqlCount runs the query, and scans all its rows.
+func rsqlCount(db *sql.DB, query string) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var njet uint32
+	for rows.Next() {
+		err = rows.Scan(&njet)
+		if err != nil {
+			return err
+		}
+	}
+	err = rows.Err()
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,359 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-hep/examples/groot/progress"
+	"go-hep.org/x/hep/groot/rtree"
+	"go-hep.org/x/hep/hbook"
+)
+
+// task is an analysis fed by a scan of the Events tree,
+// possibly shared with other analyses.
+type task struct {
+	bench *Bench
+	ana   Analysis
+	hs    []hbook.Histogram
+
+	skim   *skimmer      // skim of the events passing the selection, if any
+	friend *friendWriter // friend tree of the quantities derived from each event, if any
+	cpy    []varCopy     // variables loaded on behalf of another analysis
+	delta  time.Duration // time spent processing events
+	nevts  int64         // number of processed events
+	done   bool          // whether the analysis failed and should not be fed anymore
+	err    error
+}
+
+// vars returns the branches read by the task: the ones of its analysis,
+// and the ones written by its skimmer, if any.
+func (t *task) vars() []rtree.ScanVar {
+	vars := t.ana.Vars()
+	if t.skim != nil {
+		vars = append(vars[:len(vars):len(vars)], t.skim.vars...)
+	}
+	return vars
+}
+
+// varCopy copies the value of a variable loaded by the shared scanner
+// into the variable bound by an analysis to the same branch.
+type varCopy struct {
+	dst reflect.Value
+	src reflect.Value
+}
+
+// newTasks creates and books the analyses of the provided benchmarks.
+func newTasks(benchs []*Bench) []*task {
+	tasks := make([]*task, len(benchs))
+	for i, bench := range benchs {
+		ana := bench.New()
+		tasks[i] = &task{
+			bench: bench,
+			ana:   ana,
+			hs:    ana.Book(),
+		}
+	}
+	return tasks
+}
+
+// runShared runs all the provided benchmarks during a single scan of the
+// Events tree, over the union of the branches they need.
+// Benchmarks processing the whole tree at once are run one after the other.
+// When the context is canceled, the partial results of the single pass
+// are saved and the remaining benchmarks are not run.
+func runShared(ctx context.Context, ids []string, cfg config) error {
+	var (
+		benchs []*Bench
+		names  []string
+		whole  []string // benchmarks processing the whole tree at once
+	)
+	for _, id := range ids {
+		bench, ok := benchIDs[id]
+		if !ok {
+			return fmt.Errorf("no such OpenData example: %q", id)
+		}
+		if _, ok := bench.New().(TreeAnalysis); ok {
+			whole = append(whole, id)
+			continue
+		}
+		benchs = append(benchs, bench)
+		names = append(names, id)
+	}
+
+	allGood := true
+	if len(benchs) > 0 {
+		log.Printf("running %q in a single pass...", names)
+		rep := newProgress(strings.Join(names, ","), cfg)
+		beg := newUsage()
+		tasks, perr := runTasks(progress.NewContext(ctx, rep), benchs, cfg)
+		end := newUsage()
+		rep.Close()
+		log.Printf("running %q in a single pass... [err=%v] delta=%v", names, perr, end.wall.Sub(beg.wall))
+		printIO(beg, end)
+		if perr != nil && !interrupted(perr) {
+			return fmt.Errorf("could not run benchs %q: %w", names, perr)
+		}
+
+		for _, t := range tasks {
+			err := t.err
+			if err == nil {
+				err = finish(t.bench, t.ana, t.hs, t.nevts, cfg.out, perr)
+			}
+			log.Printf("running %q... [err=%v] entries=%d delta=%v", t.bench.Name, err, t.nevts, t.delta)
+			// CPU time, bytes read and I/O are only known for the whole pass.
+			cfg.report.addTimed(t.bench, cfg, t.nevts, t.hs, t.ana.CutFlow(), t.delta.Seconds(), beg, end, err)
+			switch {
+			case err == nil:
+			case interrupted(err):
+				log.Printf("%q interrupted after %d entries: partial results saved", t.bench.Name, t.nevts)
+			default:
+				log.Printf("could not run bench %q: %v", t.bench.Name, err)
+				allGood = false
+			}
+		}
+
+		if perr != nil {
+			return perr
+		}
+	}
+
+	for _, id := range whole {
+		err := runBench(ctx, id, cfg)
+		if interrupted(err) {
+			return err
+		}
+		if err != nil {
+			log.Printf("could not run bench %q: %v", id, err)
+			allGood = false
+		}
+	}
+
+	if !allGood {
+		return fmt.Errorf("at least one benchmark failed")
+	}
+
+	return nil
+}
+
+// runTasks runs the provided benchmarks during a single scan of the
+// Events tree of the input file, possibly split over multiple workers,
+// and checkpointed.
+// When the context is canceled, runTasks returns the tasks fed so far
+// together with the error of the context.
+func runTasks(ctx context.Context, benchs []*Bench, cfg config) ([]*task, error) {
+	ch, err := openTree(cfg.fname)
+	if err != nil {
+		return nil, err
+	}
+	defer ch.Close()
+
+	fmt.Printf("tree: %d entries\n", ch.entries())
+
+	tasks := newTasks(benchs)
+	if cfg.skim != nil {
+		if cfg.nworkers > 1 {
+			return nil, fmt.Errorf("skimming can not be used with concurrent workers")
+		}
+		for _, t := range tasks {
+			t.skim, err = cfg.skim.newSkimmer(t, ch.trees[0])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if cfg.friend != nil {
+		switch {
+		case cfg.nworkers > 1:
+			return nil, fmt.Errorf("friend trees can not be written by concurrent workers")
+		case !cfg.rctx.IsAll():
+			return nil, fmt.Errorf("friend trees require processing all the entries")
+		}
+		for _, t := range tasks {
+			t.friend, err = cfg.friend.newFriendWriter(t)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	process := func(rctx RunContext) error {
+		return scanTasks(ctx, tasks, ch, rctx)
+	}
+	if cfg.nworkers > 1 {
+		process = func(rctx RunContext) error {
+			return runParallel(ctx, tasks, cfg.fname, ch.entries(), rctx, cfg.nworkers)
+		}
+	}
+
+	err = cfg.ckpt.run(ctx, tasks, ch, cfg, process)
+	for _, t := range tasks {
+		serr := t.skim.close(t.bench, cfg, err)
+		if serr != nil && t.err == nil {
+			t.err = serr
+		}
+		ferr := t.friend.close(t.bench, cfg, err)
+		if ferr != nil && t.err == nil {
+			t.err = ferr
+		}
+	}
+	if err != nil && !interrupted(err) {
+		return nil, err
+	}
+
+	return tasks, err
+}
+
+// scanTasks feeds the entries of the chain selected by the run context to
+// the provided tasks, reading the union of the branches they need only once.
+func scanTasks(ctx context.Context, tasks []*task, ch *chain, rctx RunContext) error {
+	var (
+		vars []rtree.ScanVar
+		idx  = make(map[string]int) // index of branch+leaf into vars
+	)
+	for _, t := range tasks {
+		t.cpy = t.cpy[:0]
+		for _, v := range t.vars() {
+			key := v.Name + "." + v.Leaf
+			i, dup := idx[key]
+			if !dup {
+				idx[key] = len(vars)
+				vars = append(vars, v)
+				continue
+			}
+			var (
+				src = reflect.ValueOf(vars[i].Value).Elem()
+				dst = reflect.ValueOf(v.Value).Elem()
+			)
+			if src.Type() != dst.Type() {
+				return fmt.Errorf(
+					"type mismatch for branch %q: %q wants %v, already read as %v",
+					v.Name, t.bench.Name, dst.Type(), src.Type(),
+				)
+			}
+			t.cpy = append(t.cpy, varCopy{dst: dst, src: src})
+		}
+	}
+
+	for i, tree := range ch.trees {
+		n := tree.Entries()
+		local := rctx.local(ch.offs[i], n)
+		if local.Entries(n) == 0 {
+			continue
+		}
+
+		err := scanTree(ctx, tasks, vars, tree, ch.io[i], ch.offs[i], local)
+		if err != nil {
+			return fmt.Errorf("could not scan %q: %w", ch.names[i], err)
+		}
+
+		if done(tasks) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// scanTree feeds the entries of the tree selected by the run context to
+// the provided tasks, until the context is canceled.
+// off is the index in the chain of the first entry of the tree.
+// Scanned entries are reported to the progress reporter of the context,
+// and the time spent loading and processing them to the I/O accounting
+// of the file of the tree, if any.
+func scanTree(ctx context.Context, tasks []*task, vars []rtree.ScanVar, tree rtree.Tree, fio *fileIO, off int64, rctx RunContext) error {
+	rsc, err := rtree.NewScannerVars(tree, vars...)
+	if err != nil {
+		return fmt.Errorf("could not create scanner: %w", err)
+	}
+	defer rsc.Close()
+
+	sc := progress.NewScanner(rsc, progress.FromContext(ctx))
+
+	if rctx.Start > 0 {
+		err = sc.SeekEntry(rctx.Start)
+		if err != nil {
+			return fmt.Errorf("could not seek to entry %d: %w", off+rctx.Start, err)
+		}
+	}
+
+	var (
+		end  = rctx.End(tree.Entries())
+		stop = ctx.Done()
+	)
+	for sc.Next() {
+		select {
+		case <-stop:
+			return ctx.Err()
+		default:
+		}
+
+		if sc.Entry() >= end {
+			break
+		}
+		if !rctx.Selects(sc.Entry()) {
+			continue
+		}
+
+		m := fio.mark()
+		err := sc.Scan()
+		if err != nil {
+			return fmt.Errorf("error during scan: %w", err)
+		}
+		fio.loaded(m)
+
+		for _, t := range tasks {
+			if t.done {
+				continue
+			}
+
+			beg := time.Now()
+			for _, c := range t.cpy {
+				c.dst.Set(c.src)
+			}
+			mark := t.skim.mark()
+			err := t.ana.Process()
+			delta := time.Since(beg)
+			t.delta += delta
+			t.nevts++
+			fio.processed(delta)
+			if err == nil {
+				err = t.skim.write(mark)
+			}
+			if err == nil {
+				err = t.friend.write()
+			}
+			if err != nil {
+				t.err = fmt.Errorf("could not process entry %d: %w", off+sc.Entry(), err)
+				t.done = true
+			}
+		}
+
+		if done(tasks) {
+			break
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("could not scan whole tree: %w", err)
+	}
+
+	return nil
+}
+
+// done returns whether none of the tasks should be fed anymore.
+func done(tasks []*task) bool {
+	for _, t := range tasks {
+		if !t.done {
+			return false
+		}
+	}
+	return true
+}
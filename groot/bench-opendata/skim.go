@@ -0,0 +1,193 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// skimFile is a ROOT file where the events passing the selection of
+// analyses are written, under a directory per analysis.
+type skimFile struct {
+	*treeFile
+	branches []string // branches (or glob patterns) to write; the ones read by the analysis if empty
+}
+
+// newSkimFile creates the named skim file.
+// branches is a comma-separated list of branches (or glob patterns of
+// branches) to write, the ones read by each analysis if empty.
+func newSkimFile(fname, branches string) (*skimFile, error) {
+	var pats []string
+	for _, v := range strings.Split(branches, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if _, err := path.Match(v, ""); err != nil {
+			return nil, fmt.Errorf("invalid branch pattern %q: %w", v, err)
+		}
+		pats = append(pats, v)
+	}
+
+	tf, err := createTreeFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("could not create skim file: %w", err)
+	}
+
+	return &skimFile{treeFile: tf, branches: pats}, nil
+}
+
+// Close closes the skim file.
+func (sf *skimFile) Close() error {
+	if sf == nil {
+		return nil
+	}
+	return sf.treeFile.Close()
+}
+
+// skimmer writes the entries of the Events trees passing the selection of
+// an analysis into a new Events tree.
+// An entry passes the selection when it passes the last cut of the
+// cut-flow of the analysis.
+type skimmer struct {
+	w    *treeWriter
+	vars []rtree.ScanVar // branches to read, bound to the values written
+	cf   *CutFlow
+
+	nevts int64 // number of entries given to the analysis
+}
+
+// newSkimmer creates the skimmer of the provided task, writing the chosen
+// branches of the tree (together with the count branches of the chosen
+// jagged branches) into the <benchmark>/Events tree of the skim file.
+// newSkimmer returns nil if sf is nil.
+func (sf *skimFile) newSkimmer(t *task, tree rtree.Tree) (*skimmer, error) {
+	if sf == nil {
+		return nil, nil
+	}
+
+	cf := t.ana.CutFlow()
+	if cf == nil || len(cf.Cuts) == 0 {
+		return nil, fmt.Errorf("could not skim %q: no cut-flow", t.bench.Name)
+	}
+
+	chosen := make(map[string]bool)
+	switch {
+	case len(sf.branches) == 0:
+		for _, v := range t.ana.Vars() {
+			chosen[v.Name] = true
+		}
+	default:
+		for _, b := range tree.Branches() {
+			for _, pat := range sf.branches {
+				if ok, _ := path.Match(pat, b.Name()); ok {
+					chosen[b.Name()] = true
+				}
+			}
+		}
+		for _, pat := range sf.branches {
+			if !strings.ContainsAny(pat, "*?[") && tree.Branch(pat) == nil {
+				return nil, fmt.Errorf("could not skim %q: no branch %q", t.bench.Name, pat)
+			}
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, fmt.Errorf("could not skim %q: no branch matching %q", t.bench.Name, sf.branches)
+	}
+
+	counts := make(map[string]string) // name of the count branch of each jagged branch
+	for name := range chosen {
+		b := tree.Branch(name)
+		if b == nil {
+			return nil, fmt.Errorf("could not skim %q: no branch %q", t.bench.Name, name)
+		}
+		if lc := b.Leaves()[0].LeafCount(); lc != nil {
+			counts[name] = lc.Name()
+			chosen[lc.Name()] = true
+		}
+	}
+
+	// write the branches in the order of the input tree, count branches
+	// first: slices refer to an already written count branch.
+	var (
+		vars  []rtree.ScanVar
+		wvars []rtree.WriteVar
+		rvars = rtree.NewScanVars(tree)
+	)
+	for _, pass := range []bool{true, false} {
+		for _, v := range rvars {
+			if !chosen[v.Name] {
+				continue
+			}
+			_, jagged := counts[v.Name]
+			if jagged == pass {
+				continue
+			}
+			// all the branches have a single leaf, read like analyses do.
+			vars = append(vars, rtree.ScanVar{Name: v.Name, Value: v.Value})
+			wvars = append(wvars, rtree.WriteVar{Name: v.Name, Value: v.Value, Count: counts[v.Name]})
+		}
+	}
+
+	w, err := sf.newWriter(t.bench, "Events", "Events passing "+t.bench.Name, wvars)
+	if err != nil {
+		return nil, fmt.Errorf("could not skim %q: %w", t.bench.Name, err)
+	}
+
+	return &skimmer{w: w, vars: vars, cf: cf}, nil
+}
+
+// mark returns the number of events having passed the selection so far.
+func (sk *skimmer) mark() int64 {
+	if sk == nil {
+		return 0
+	}
+	return sk.cf.Cuts[len(sk.cf.Cuts)-1].N
+}
+
+// write writes the current entry if it passed the selection since the
+// provided mark.
+func (sk *skimmer) write(mark int64) error {
+	if sk == nil {
+		return nil
+	}
+	sk.nevts++
+	if sk.mark() == mark {
+		return nil
+	}
+
+	err := sk.w.write()
+	if err != nil {
+		return fmt.Errorf("could not write skimmed entry: %w", err)
+	}
+	return nil
+}
+
+// close closes the skim tree, and records its provenance: the source
+// files, the selection and the number of written entries.
+func (sk *skimmer) close(bench *Bench, cfg config, err error) error {
+	if sk == nil {
+		return nil
+	}
+
+	cut := sk.cf.Cuts[len(sk.cf.Cuts)-1].Name
+	lines := []string{
+		fmt.Sprintf("selection: %s (%s): %q", bench.Name, bench.Doc, cut),
+		fmt.Sprintf("entries: %d of %d processed entries", sk.w.n, sk.nevts),
+	}
+	if interrupted(err) {
+		lines = append(lines, fmt.Sprintf("partial: %v", err))
+	}
+
+	cerr := sk.w.close(cfg, lines...)
+	if cerr != nil {
+		return fmt.Errorf("could not close skim tree of %q: %w", bench.Name, cerr)
+	}
+	return nil
+}
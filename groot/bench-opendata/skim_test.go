@@ -0,0 +1,211 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rbase"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+func TestSkim(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fname := filepath.Join(dir, "events.root")
+	err = createTestFile(fname, 2000)
+	if err != nil {
+		t.Fatalf("could not create input file: %+v", err)
+	}
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		branches string
+		rctx     RunContext
+		want     []string // skimmed branches
+	}{
+		{
+			name: "05-basic",
+			want: []string{"MET_pt", "Muon_charge", "Muon_eta", "Muon_mass", "Muon_phi", "Muon_pt", "nMuon"},
+		},
+		{
+			name:     "05-struct",
+			branches: "Muon_*, MET_pt",
+			rctx:     RunContext{Start: 100, Stride: 2},
+			want:     []string{"MET_pt", "Muon_charge", "Muon_eta", "Muon_mass", "Muon_phi", "Muon_pt", "nMuon"},
+		},
+		{
+			name:     "04-basic",
+			branches: "nJet",
+			want:     []string{"nJet"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			skim := filepath.Join(dir, tc.name+".root")
+			sf, err := newSkimFile(skim, tc.branches)
+			if err != nil {
+				t.Fatalf("could not create skim file: %+v", err)
+			}
+
+			bench := benchIDs[tc.name]
+			hs, cf, nevts, err := run(context.Background(), bench, config{fname: fname, rctx: tc.rctx, out: out, skim: sf})
+			if err != nil {
+				t.Fatalf("could not run %q: %+v", tc.name, err)
+			}
+			err = sf.Close()
+			if err != nil {
+				t.Fatalf("could not close skim file: %+v", err)
+			}
+
+			f, err := groot.Open(skim)
+			if err != nil {
+				t.Fatalf("could not open skim file: %+v", err)
+			}
+			defer f.Close()
+
+			tree := getSkim(t, f, tc.name, "Events").(rtree.Tree)
+			npass := cf.Cuts[len(cf.Cuts)-1].N
+			if got := tree.Entries(); got != npass {
+				t.Fatalf("invalid number of skimmed entries: got=%d, want=%d", got, npass)
+			}
+
+			var got []string
+			for _, b := range tree.Branches() {
+				got = append(got, b.Name())
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("invalid skimmed branches:\ngot= %q\nwant=%q", got, tc.want)
+			}
+
+			prov := getSkim(t, f, tc.name, "provenance").(*rbase.ObjString).String()
+			for _, want := range []string{
+				"source: " + fname + "\n",
+				fmt.Sprintf("selection: %s (%s): %q\n", bench.Name, bench.Doc, cf.Cuts[len(cf.Cuts)-1].Name),
+				fmt.Sprintf("entries: %d of %d processed entries\n", npass, nevts),
+			} {
+				if !strings.Contains(prov, want) {
+					t.Fatalf("invalid provenance:\ngot:\n%s\nwant: %q", prov, want)
+				}
+			}
+
+			if tc.branches != "" {
+				return
+			}
+
+			// all the skimmed events pass the selection, and fill the same
+			// histograms as the original run.
+			ana := bench.New()
+			rhs := ana.Book()
+			sc, err := rtree.NewScannerVars(tree, ana.Vars()...)
+			if err != nil {
+				t.Fatalf("could not create scanner: %+v", err)
+			}
+			defer sc.Close()
+			for sc.Next() {
+				err := sc.Scan()
+				if err != nil {
+					t.Fatalf("could not scan skimmed entry %d: %+v", sc.Entry(), err)
+				}
+				err = ana.Process()
+				if err != nil {
+					t.Fatalf("could not process skimmed entry %d: %+v", sc.Entry(), err)
+				}
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatalf("could not scan skim: %+v", err)
+			}
+
+			rcf := ana.CutFlow()
+			if got := rcf.Cuts[len(rcf.Cuts)-1].N; got != npass {
+				t.Fatalf("invalid number of skimmed events passing the selection: got=%d, want=%d", got, npass)
+			}
+			err = cmpHists(rhs, hs, 0)
+			if err != nil {
+				t.Fatalf("skimmed and original histograms differ: %+v", err)
+			}
+		})
+	}
+}
+
+func TestSkimErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bench-opendata-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	out, err := newOutput(dir, "png", "")
+	if err != nil {
+		t.Fatalf("could not create output: %+v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		branches string
+		nworkers int
+		err      string
+	}{
+		{name: "05-arrow", err: `could not skim "05-arrow": analyses processing whole trees can not be skimmed`},
+		{name: "05-basic", branches: "Muon_pt,Foo", err: `could not skim "05-basic": no branch "Foo"`},
+		{name: "05-basic", branches: "Foo*", err: `could not skim "05-basic": no branch matching ["Foo*"]`},
+		{name: "05-basic", nworkers: 2, err: "skimming can not be used with concurrent workers"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sf, err := newSkimFile(filepath.Join(dir, "skim.root"), tc.branches)
+			if err != nil {
+				t.Fatalf("could not create skim file: %+v", err)
+			}
+			defer sf.Close()
+
+			cfg := config{fname: goldenInput, nworkers: tc.nworkers, out: out, skim: sf}
+			_, _, _, err = run(context.Background(), benchIDs[tc.name], cfg)
+			switch {
+			case err == nil:
+				t.Fatalf("expected an error")
+			case err.Error() != tc.err:
+				t.Fatalf("invalid error:\ngot= %v\nwant=%s", err, tc.err)
+			}
+		})
+	}
+
+	_, err = newSkimFile(filepath.Join(dir, "skim.root"), "Muon_[")
+	if err == nil {
+		t.Fatalf("expected an error for an invalid branch pattern")
+	}
+}
+
+func getSkim(t *testing.T, f *riofs.File, bench, name string) interface{} {
+	t.Helper()
+
+	o, err := riofs.Dir(f).Get(bench)
+	if err != nil {
+		t.Fatalf("could not retrieve skim directory %q: %+v", bench, err)
+	}
+	o, err = o.(riofs.Directory).Get(name)
+	if err != nil {
+		t.Fatalf("could not retrieve %s/%s: %+v", bench, name, err)
+	}
+	return o
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// confidence is the confidence level of the intervals on the mean
+// wall time of the analyses.
+const confidence = 0.95
+
+// summary describes the distribution of the wall times of the repeated
+// runs of an analysis, in seconds.
+type summary struct {
+	name   string
+	n      int
+	min    float64
+	median float64
+	mean   float64
+	stddev float64
+	ci     float64 // half-width of the confidence interval on the mean
+}
+
+// summarize computes the summary of the provided sample.
+func summarize(name string, vs []float64) summary {
+	sum := summary{name: name, n: len(vs)}
+	if len(vs) == 0 {
+		return sum
+	}
+
+	xs := make([]float64, len(vs))
+	copy(xs, vs)
+	sort.Float64s(xs)
+
+	sum.min = xs[0]
+	switch n := len(xs); n % 2 {
+	case 0:
+		sum.median = 0.5 * (xs[n/2-1] + xs[n/2])
+	default:
+		sum.median = xs[n/2]
+	}
+
+	if len(xs) < 2 {
+		sum.mean = xs[0]
+		return sum
+	}
+
+	sum.mean, sum.stddev = stat.MeanStdDev(xs, nil)
+	t := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: float64(len(xs) - 1)}
+	sum.ci = t.Quantile(0.5+0.5*confidence) * sum.stddev / math.Sqrt(float64(len(xs)))
+	return sum
+}
+
+// summaries summarizes the wall times of the complete, successful runs of each
+// analysis of the report, in the order the analyses were first run.
+func (rep *Report) summaries() []summary {
+	var (
+		names []string
+		walls = make(map[string][]float64)
+	)
+	for _, ana := range rep.Analyses {
+		if ana.Error != "" || ana.Partial {
+			continue
+		}
+		if _, dup := walls[ana.Name]; !dup {
+			names = append(names, ana.Name)
+		}
+		walls[ana.Name] = append(walls[ana.Name], ana.WallTime)
+	}
+
+	sums := make([]summary, len(names))
+	for i, name := range names {
+		sums[i] = summarize(name, walls[name])
+	}
+	return sums
+}
+
+// writeStats writes a table summarizing the wall times of the runs of each
+// analysis of the report, in a benchstat-like fashion: the mean wall time
+// is followed by the relative half-width of its confidence interval.
+func (rep *Report) writeStats(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "name\ttime/op\truns\tmin\tmedian\tmean\tstddev\t%g%% CI\n", 100*confidence)
+	for _, sum := range rep.summaries() {
+		pct := 0.0
+		if sum.mean > 0 {
+			pct = 100 * sum.ci / sum.mean
+		}
+		fmt.Fprintf(
+			tw, "%s\t%v ± %.0f%%\t%d\t%v\t%v\t%v\t%v\t[%v, %v]\n",
+			sum.name, fmtSeconds(sum.mean), pct, sum.n,
+			fmtSeconds(sum.min), fmtSeconds(sum.median), fmtSeconds(sum.mean),
+			fmtSeconds(sum.stddev),
+			fmtSeconds(sum.mean-sum.ci), fmtSeconds(sum.mean+sum.ci),
+		)
+	}
+	return tw.Flush()
+}
+
+// writeBenchFmt writes the complete, successful runs of the report in the Go
+// benchmark text format, so they can be compared with benchstat.
+func (rep *Report) writeBenchFmt(w io.Writer) error {
+	_, err := fmt.Fprintf(
+		w, "goos: %s\ngoarch: %s\npkg: github.com/go-hep/examples/groot/bench-opendata\n",
+		runtime.GOOS, runtime.GOARCH,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, ana := range rep.Analyses {
+		if ana.Error != "" || ana.Partial {
+			continue
+		}
+		mbps := 0.0
+		if ana.WallTime > 0 {
+			mbps = float64(ana.BytesRead) / ana.WallTime / 1e6
+		}
+		_, err := fmt.Fprintf(
+			w, "BenchmarkOpenData/%s \t1\t%d ns/op\t%.2f MB/s\t%.0f events/s\n",
+			ana.Name, int64(ana.WallTime*1e9), mbps, ana.Rate,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fmtSeconds(v float64) string {
+	return time.Duration(v * float64(time.Second)).Round(time.Microsecond).String()
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	for _, tc := range []struct {
+		vs   []float64
+		want summary
+	}{
+		{
+			vs:   nil,
+			want: summary{name: "x"},
+		},
+		{
+			vs:   []float64{2},
+			want: summary{name: "x", n: 1, min: 2, median: 2, mean: 2},
+		},
+		{
+			vs: []float64{4, 1, 3, 2},
+			want: summary{
+				name: "x", n: 4, min: 1, median: 2.5, mean: 2.5,
+				stddev: 1.2909944487358056,
+				ci:     2.054260256760521, // t(0.975, 3) * stddev / 2
+			},
+		},
+		{
+			vs: []float64{5, 1, 3},
+			want: summary{
+				name: "x", n: 3, min: 1, median: 3, mean: 3,
+				stddev: 2,
+				ci:     4.9682754235006605, // t(0.975, 2) * stddev / sqrt(3)
+			},
+		},
+	} {
+		got := summarize("x", tc.vs)
+		if got.name != tc.want.name || got.n != tc.want.n ||
+			!approxEqual(got.min, tc.want.min, 1e-12) ||
+			!approxEqual(got.median, tc.want.median, 1e-12) ||
+			!approxEqual(got.mean, tc.want.mean, 1e-12) ||
+			!approxEqual(got.stddev, tc.want.stddev, 1e-12) ||
+			!approxEqual(got.ci, tc.want.ci, 1e-9) {
+			t.Fatalf("invalid summary of %v:\ngot= %+v\nwant=%+v", tc.vs, got, tc.want)
+		}
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	var (
+		rep  = new(Report)
+		cfg  = config{count: 3, warmup: 2, report: rep}
+		runs = 0
+	)
+	err := repeat(cfg, func(cfg config) error {
+		runs++
+		cfg.report.addTimed(&Bench{Name: "01-basic"}, cfg, 10, nil, nil, 2, usage{}, usage{}, nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not repeat: %+v", err)
+	}
+
+	if got, want := runs, 5; got != want {
+		t.Fatalf("invalid number of runs: got=%d, want=%d", got, want)
+	}
+	if got, want := len(rep.Analyses), 3; got != want {
+		t.Fatalf("invalid number of measured runs: got=%d, want=%d", got, want)
+	}
+	for i, ana := range rep.Analyses {
+		if got, want := ana.Run, i+1; got != want {
+			t.Fatalf("invalid run number: got=%d, want=%d", got, want)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	err = rep.writeBenchFmt(buf)
+	if err != nil {
+		t.Fatalf("could not write benchmark format: %+v", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "Benchmark") {
+			lines = append(lines, line)
+		}
+	}
+	if got, want := len(lines), 3; got != want {
+		t.Fatalf("invalid number of benchmark lines: got=%d, want=%d", got, want)
+	}
+	if got, want := lines[0], "BenchmarkOpenData/01-basic \t1\t2000000000 ns/op\t0.00 MB/s\t5 events/s"; got != want {
+		t.Fatalf("invalid benchmark line:\ngot= %q\nwant=%q", got, want)
+	}
+}
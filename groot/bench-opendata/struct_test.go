@@ -0,0 +1,57 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-hep/examples/groot/bench-opendata/nanoaod"
+)
+
+func TestEventLoad(t *testing.T) {
+	var evt Event
+	vars := evt.Vars("Muon_pt", "Muon_charge", "Jet_eta", "MET_sumet")
+	if got, want := len(vars), 4; got != want {
+		t.Fatalf("invalid number of scan-vars: got=%d, want=%d", got, want)
+	}
+
+	*vars[0].Value.(*[]float32) = []float32{10, 20}
+	*vars[1].Value.(*[]int32) = []int32{-1, +1}
+	*vars[2].Value.(*[]float32) = []float32{0.5}
+	*vars[3].Value.(*float32) = 42
+
+	evt.Load()
+
+	want := Event{
+		Muons: nanoaod.Muons{{Pt: 10, Charge: -1}, {Pt: 20, Charge: +1}},
+		Jets:  nanoaod.Jets{{Eta: 0.5}},
+		MET:   MET{SumEt: 42},
+	}
+
+	if !reflect.DeepEqual(evt.Muons, want.Muons) {
+		t.Fatalf("invalid muons:\ngot= %+v\nwant=%+v", evt.Muons, want.Muons)
+	}
+	if len(evt.Electrons) != 0 {
+		t.Fatalf("invalid electrons: %+v", evt.Electrons)
+	}
+	if !reflect.DeepEqual(evt.Jets, want.Jets) {
+		t.Fatalf("invalid jets:\ngot= %+v\nwant=%+v", evt.Jets, want.Jets)
+	}
+	if evt.MET != want.MET {
+		t.Fatalf("invalid MET:\ngot= %+v\nwant=%+v", evt.MET, want.MET)
+	}
+}
+
+func TestEventVarsInvalid(t *testing.T) {
+	defer func() {
+		if e := recover(); e == nil {
+			t.Fatalf("expected a panic")
+		}
+	}()
+
+	var evt Event
+	_ = evt.Vars("Tau_pt")
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rbase"
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+// treeFile is a ROOT file where trees are written while analyses run,
+// under a directory per analysis, together with their provenance.
+type treeFile struct {
+	mu sync.Mutex
+	f  *riofs.File
+}
+
+// createTreeFile creates the named tree file.
+func createTreeFile(fname string) (*treeFile, error) {
+	err := os.MkdirAll(filepath.Dir(fname), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create output directory: %w", err)
+	}
+
+	f, err := groot.Create(fname)
+	if err != nil {
+		return nil, fmt.Errorf("could not create ROOT file: %w", err)
+	}
+
+	return &treeFile{f: f}, nil
+}
+
+// Close closes the tree file.
+func (tf *treeFile) Close() error {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	err := tf.f.Close()
+	if err != nil {
+		return fmt.Errorf("could not close ROOT file: %w", err)
+	}
+	return nil
+}
+
+// treeWriter writes a tree of a tree file.
+type treeWriter struct {
+	tf  *treeFile
+	dir riofs.Directory
+	w   rtree.Writer
+	n   int64 // number of written entries
+}
+
+// newWriter creates the named tree under the directory of the benchmark,
+// with the provided title and branches.
+func (tf *treeFile) newWriter(bench *Bench, name, title string, wvars []rtree.WriteVar) (*treeWriter, error) {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	dir, err := riofs.Dir(tf.f).Mkdir(bench.Name)
+	if err != nil {
+		return nil, fmt.Errorf("could not create directory %q: %w", bench.Name, err)
+	}
+
+	w, err := rtree.NewWriter(dir, name, wvars, rtree.WithTitle(title))
+	if err != nil {
+		return nil, fmt.Errorf("could not create tree %s/%s: %w", bench.Name, name, err)
+	}
+
+	return &treeWriter{tf: tf, dir: dir, w: w}, nil
+}
+
+// write writes the current values of the branches as a new entry.
+func (tw *treeWriter) write() error {
+	tw.tf.mu.Lock()
+	defer tw.tf.mu.Unlock()
+
+	_, err := tw.w.Write()
+	if err != nil {
+		return err
+	}
+	tw.n++
+	return nil
+}
+
+// close closes the tree, and records its provenance next to it, as a
+// TObjString.
+// The provenance lists the input files of the run, followed by the
+// provided lines.
+func (tw *treeWriter) close(cfg config, lines ...string) error {
+	tw.tf.mu.Lock()
+	defer tw.tf.mu.Unlock()
+
+	err := tw.w.Close()
+	if err != nil {
+		return fmt.Errorf("could not close tree: %w", err)
+	}
+
+	o := new(strings.Builder)
+	fmt.Fprintf(o, "source: %s\n", cfg.fname)
+	for _, f := range cfg.inputs {
+		fmt.Fprintf(o, "file: %s (%d entries)\n", f.name, f.entries)
+	}
+	for _, line := range lines {
+		fmt.Fprintf(o, "%s\n", line)
+	}
+
+	err = tw.dir.Put("provenance", rbase.NewObjString(o.String()))
+	if err != nil {
+		return fmt.Errorf("could not record provenance: %w", err)
+	}
+
+	return nil
+}
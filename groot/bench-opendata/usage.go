@@ -0,0 +1,142 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/xrootd/xrdio"
+)
+
+// bytesRead is the number of bytes read from all the input files.
+var bytesRead int64
+
+// usage is a snapshot of the resources used by the process.
+type usage struct {
+	wall    time.Time
+	cpu     time.Duration // user+system CPU time
+	bytes   int64         // bytes read from input files
+	peakRSS int64         // peak resident set size of the process since it started, in bytes
+
+	io        ioStats // I/O accounting of the closed input files
+	ioEnabled bool    // whether the I/O accounting is enabled
+}
+
+func newUsage() usage {
+	cpu, rss := rusage()
+	io, ok := ioSnapshot()
+	return usage{
+		wall:      time.Now(),
+		cpu:       cpu,
+		bytes:     atomic.LoadInt64(&bytesRead),
+		peakRSS:   rss,
+		io:        io,
+		ioEnabled: ok,
+	}
+}
+
+// countingReader is a ROOT file reader accounting for the bytes read
+// from the underlying reader and, with -io, for the baskets read.
+type countingReader struct {
+	riofs.Reader
+	io *fileIO // I/O accounting of the file, if enabled
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	atomic.AddInt64(&bytesRead, int64(n))
+	return n, err
+}
+
+func (r *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	if r.io == nil {
+		n, err := r.Reader.ReadAt(p, off)
+		atomic.AddInt64(&bytesRead, int64(n))
+		return n, err
+	}
+
+	beg := time.Now()
+	n, err := r.Reader.ReadAt(p, off)
+	atomic.AddInt64(&bytesRead, int64(n))
+	r.io.read(p[:n], beg)
+	return n, err
+}
+
+// rawReaders opens the readers of the ROOT files of the remote URL schemes
+// whose riofs plugin is registered (e.g. by the blank import of the xrootd
+// plugin in main.go).
+// riofs does not expose the readers opened by its plugins: openFile opens
+// them itself, to account for the bytes read from them.
+var rawReaders = map[string]func(path string) (riofs.Reader, error){
+	"root":  openXrdFile,
+	"xroot": openXrdFile,
+}
+
+func openXrdFile(path string) (riofs.Reader, error) {
+	return xrdio.Open(path)
+}
+
+// urlScheme returns the URL scheme of the named file, "file" for local
+// files.
+func urlScheme(fname string) string {
+	u, err := url.Parse(fname)
+	if err != nil || u.Scheme == "" {
+		return "file"
+	}
+	return u.Scheme
+}
+
+// hasDriver returns whether a riofs plugin is registered for the provided
+// URL scheme.
+func hasDriver(scheme string) bool {
+	for _, name := range riofs.Drivers() {
+		if name == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// openFile opens the named ROOT file for reading: a local file, or a file
+// of a URL scheme with a registered riofs plugin.
+// openFile also returns the I/O accounting of the file, nil unless
+// enabled with -io.
+// The reads of files opened through a plugin without raw reader (see
+// rawReaders) are not accounted for.
+func openFile(fname string) (*riofs.File, *fileIO, error) {
+	var (
+		scheme = urlScheme(fname)
+		r      riofs.Reader
+		err    error
+	)
+	switch open, ok := rawReaders[scheme]; {
+	case scheme == "file":
+		r, err = os.Open(strings.TrimPrefix(fname, "file://"))
+	case !hasDriver(scheme):
+		return nil, nil, fmt.Errorf("no riofs plugin to open %q (scheme=%s)", fname, scheme)
+	case ok:
+		r, err = open(fname)
+	default:
+		f, err := riofs.Open(fname)
+		return f, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fio := newFileIO()
+	f, err := riofs.NewReader(&countingReader{Reader: r, io: fio})
+	if err != nil {
+		r.Close()
+		return nil, nil, err
+	}
+	return f, fio, nil
+}
@@ -0,0 +1,17 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import "time"
+
+// rusage returns the CPU time used by the process and its peak resident
+// set size, in bytes.
+// rusage is not implemented on this platform.
+func rusage() (time.Duration, int64) {
+	return 0, 0
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// rusage returns the CPU time used by the process and its peak resident
+// set size, in bytes.
+func rusage() (time.Duration, int64) {
+	var ru syscall.Rusage
+	err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru)
+	if err != nil {
+		return 0, 0
+	}
+
+	cpu := time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+	rss := int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" {
+		rss *= 1024 // kilobytes
+	}
+	return cpu, rss
+}
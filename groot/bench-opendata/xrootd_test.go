@@ -0,0 +1,352 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/groot/riofs"
+	"go-hep.org/x/hep/xrootd"
+	"go-hep.org/x/hep/xrootd/xrdproto"
+	"go-hep.org/x/hep/xrootd/xrdproto/handshake"
+)
+
+// xrdServer is a pure-Go XRootD server, serving the files of a local
+// directory on a random local port.
+type xrdServer struct {
+	srv  *xrootd.Server
+	l    net.Listener
+	addr string
+	done chan struct{}
+}
+
+func newXrdServer(t *testing.T, dir string) *xrdServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	srv := &xrdServer{
+		srv:  xrootd.NewServer(xrootd.NewFSHandler(dir), nil),
+		l:    l,
+		addr: l.Addr().String(),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(srv.done)
+		_ = srv.srv.Serve(l)
+	}()
+
+	return srv
+}
+
+// url returns the URL of the named file, relative to the served directory.
+func (srv *xrdServer) url(name string) string {
+	return "root://" + srv.addr + "//" + name
+}
+
+// Close stops the server and drops all its connections.
+// Close is idempotent.
+func (srv *xrdServer) Close() error {
+	err := srv.srv.Shutdown(context.Background())
+	// the listener is not closed by Shutdown if Serve did not start yet.
+	srv.l.Close()
+	<-srv.done
+	return err
+}
+
+func TestXRootDDrivers(t *testing.T) {
+	for _, scheme := range []string{"root", "xroot"} {
+		if !hasDriver(scheme) {
+			t.Fatalf("no riofs plugin registered for %q: %v", scheme, riofs.Drivers())
+		}
+	}
+
+	fname := sharedInput(t)
+	srv := newXrdServer(t, filepath.Dir(fname))
+	defer srv.Close()
+
+	for _, url := range []string{
+		srv.url(filepath.Base(fname)),
+		"xroot" + strings.TrimPrefix(srv.url(filepath.Base(fname)), "root"),
+	} {
+		t.Run(urlScheme(url), func(t *testing.T) {
+			beg := atomic.LoadInt64(&bytesRead)
+			ch, err := openTree(url)
+			if err != nil {
+				t.Fatalf("could not open %q: %+v", url, err)
+			}
+			defer ch.Close()
+
+			if got, want := ch.entries(), int64(testEvents); got != want {
+				t.Fatalf("invalid number of entries: got=%d, want=%d", got, want)
+			}
+			if atomic.LoadInt64(&bytesRead) == beg {
+				t.Fatalf("no bytes read over XRootD")
+			}
+		})
+	}
+
+	_, err := openTree("nodriver://" + srv.addr + "//" + filepath.Base(fname))
+	if want := "no riofs plugin to open"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("invalid error:\ngot= %v\nwant=%s", err, want)
+	}
+}
+
+func TestXRootDErrors(t *testing.T) {
+	var (
+		fname = sharedInput(t)
+		dir   = filepath.Dir(fname)
+	)
+
+	t.Run("missing-file", func(t *testing.T) {
+		srv := newXrdServer(t, dir)
+		defer srv.Close()
+
+		fname := srv.url("missing.root")
+		_, err := openTree(fname)
+		if err == nil {
+			t.Fatalf("expected an error opening %q", fname)
+		}
+		if want := fmt.Sprintf("could not open ROOT file %q", fname); !strings.Contains(err.Error(), want) {
+			t.Fatalf("invalid error:\ngot= %v\nwant=%s", err, want)
+		}
+	})
+
+	t.Run("no-server", func(t *testing.T) {
+		srv := newXrdServer(t, dir)
+		fname := srv.url(filepath.Base(fname))
+		srv.Close()
+
+		_, err := openTree(fname)
+		if err == nil {
+			t.Fatalf("expected an error opening %q without server", fname)
+		}
+	})
+}
+
+// xrdProxy forwards the connections of XRootD clients to a server, until
+// its connections to the server are dropped (see drop).
+type xrdProxy struct {
+	l    net.Listener
+	srv  string // address of the proxied server
+	addr string
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	dropped bool
+	clis    []net.Conn // connections to the clients
+	srvs    []net.Conn // connections to the server
+}
+
+func newXrdProxy(t *testing.T, srv string) *xrdProxy {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("could not listen: %+v", err)
+	}
+
+	p := &xrdProxy{l: l, srv: srv, addr: l.Addr().String()}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				p.serve(conn)
+			}()
+		}
+	}()
+
+	return p
+}
+
+// url returns the URL of the named file, relative to the served directory.
+func (p *xrdProxy) url(name string) string {
+	return "root://" + p.addr + "//" + name
+}
+
+// addClient registers a connection to a client, closed by Close.
+func (p *xrdProxy) addClient(conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clis = append(p.clis, conn)
+}
+
+// addServer registers a connection to the server, closed by drop.
+// addServer returns false, after closing the connection, if the
+// connections to the server were dropped.
+func (p *xrdProxy) addServer(conn net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.dropped {
+		conn.Close()
+		return false
+	}
+	p.srvs = append(p.srvs, conn)
+	return true
+}
+
+// serve forwards the requests of a client to the server, and the responses
+// of the server to the client.
+// Once the connection to the server is dropped, serve answers the requests
+// of the client with an I/O error, keeping the connection to the client
+// open: the xrootd client panics when a connection of its initial session
+// is closed (see https://github.com/go-hep/hep/blob/v0.24.1/xrootd/session.go#L168-L173).
+// The connections opened by the client afterwards (e.g. to read in
+// parallel) are handshaken by the proxy itself.
+func (p *xrdProxy) serve(cli net.Conn) {
+	defer cli.Close()
+	p.addClient(cli)
+
+	var (
+		srv    net.Conn // connection to the server, nil if dropped
+		copied = make(chan struct{})
+	)
+	conn, err := net.Dial("tcp", p.srv)
+	if err == nil && p.addServer(conn) {
+		srv = conn
+		defer srv.Close()
+		go func() {
+			defer close(copied)
+			_, _ = io.Copy(cli, srv)
+		}()
+	} else {
+		close(copied)
+	}
+
+	// forward writes the provided request to the server, and returns
+	// whether it was forwarded.
+	forward := func(req []byte) bool {
+		if srv == nil {
+			return false
+		}
+		_, err := srv.Write(req)
+		return err == nil
+	}
+
+	// the handshake is not a request.
+	hs := make([]byte, handshake.RequestLength)
+	_, err = io.ReadFull(cli, hs)
+	if err != nil {
+		return
+	}
+	if !forward(hs) {
+		<-copied
+		err = xrdproto.WriteResponse(cli, xrdproto.StreamID{}, xrdproto.Ok, handshake.Response{
+			ProtocolVersion: 0x310,
+			ServerType:      xrdproto.DataServer,
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	for {
+		req, err := xrdproto.ReadRequest(cli)
+		if err != nil {
+			return
+		}
+		if forward(req) {
+			continue
+		}
+
+		// the connection to the server was dropped.
+		<-copied
+		var sid xrdproto.StreamID
+		copy(sid[:], req)
+		err = xrdproto.WriteResponse(cli, sid, xrdproto.Error, xrdproto.ServerError{
+			Code:    xrdproto.IOError,
+			Message: droppedConnectionError,
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// drop closes the connections to the server: the requests sent afterwards
+// fail with an I/O error.
+func (p *xrdProxy) drop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dropped = true
+	for _, conn := range p.srvs {
+		conn.Close()
+	}
+}
+
+// Close stops the proxy and closes all its connections.
+func (p *xrdProxy) Close() error {
+	err := p.l.Close()
+	p.drop()
+	p.mu.Lock()
+	for _, conn := range p.clis {
+		conn.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return err
+}
+
+// droppedConnectionError is the message of the errors of the requests
+// sent through a xrdProxy after its connections to the server were dropped.
+const droppedConnectionError = "connection to the server dropped by the proxy"
+
+// TestXRootDDroppedConnection checks that a run over a connection dropped
+// after the input file was opened fails with an error, rather than hangs
+// or reports partial results as complete.
+func TestXRootDDroppedConnection(t *testing.T) {
+	fname := sharedInput(t)
+
+	srv := newXrdServer(t, filepath.Dir(fname))
+	defer srv.Close()
+
+	proxy := newXrdProxy(t, srv.addr)
+	defer proxy.Close()
+
+	ch, err := openTree(proxy.url(filepath.Base(fname)))
+	if err != nil {
+		t.Fatalf("could not open input file: %+v", err)
+	}
+	defer ch.Close()
+
+	// the metadata of the Events tree was read: its baskets were not.
+	proxy.drop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tasks := newTasks([]*Bench{benchIDs["05-basic"]})
+	err = scanTasks(ctx, tasks, ch, RunContext{})
+	switch {
+	case err == nil:
+		t.Fatalf("expected an error scanning a tree over a dropped connection")
+	case ctx.Err() != nil:
+		t.Fatalf("scanning a tree over a dropped connection did not fail: %+v", err)
+	case !strings.Contains(err.Error(), droppedConnectionError):
+		t.Fatalf("invalid error:\ngot= %v\nwant=%s", err, droppedConnectionError)
+	}
+	if tasks[0].nevts != 0 {
+		t.Fatalf("invalid number of processed events: got=%d, want=0", tasks[0].nevts)
+	}
+}
@@ -12,7 +12,9 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"os"
 
+	"github.com/go-hep/examples/groot/progress"
 	"go-hep.org/x/hep/groot"
 	"go-hep.org/x/hep/groot/rtree"
 )
@@ -72,10 +74,10 @@ func tree0(fname string, evtmax int64) error {
 		log.Printf("branch[%d]: name=%q, title=%q", i, b.Name(), b.Title())
 	}
 
+	rep := progress.New(os.Stderr, evtmax, progress.WithName("groot: writing"))
+	defer rep.Close()
+
 	for i := int64(0); i < evtmax; i++ {
-		if i%1000 == 0 {
-			log.Printf("processing event %d...", i)
-		}
 		e.I = i
 		e.A.E, e.B.E = rannor()
 
@@ -93,7 +95,9 @@ func tree0(fname string, evtmax int64) error {
 		if err != nil {
 			return fmt.Errorf("could not write event %d: %w", i, err)
 		}
+		rep.Add(1)
 	}
+	rep.Close()
 
 	err = tree.Close()
 	if err != nil {
@@ -10,7 +10,9 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
 
+	"github.com/go-hep/examples/groot/progress"
 	"go-hep.org/x/hep/groot"
 	"go-hep.org/x/hep/groot/rtree"
 	"go-hep.org/x/hep/hbook"
@@ -71,16 +73,21 @@ func tree1(fname string, evtmax int64) error {
 		{Name: "evt_b_t", Value: &e.B.T},
 	}
 
-	sc, err := rtree.NewScannerVars(tree, rvars...)
+	rsc, err := rtree.NewScannerVars(tree, rvars...)
 	if err != nil {
 		return fmt.Errorf("could not create tree scanner: %w", err)
 	}
-	defer sc.Close()
+	defer rsc.Close()
 
 	if evtmax < 0 || evtmax > tree.Entries() {
 		evtmax = tree.Entries()
 	}
 
+	rep := progress.New(os.Stderr, evtmax, progress.WithName("groot: reading"))
+	defer rep.Close()
+
+	sc := progress.NewScanner(rsc, rep)
+
 	var (
 		h00 = hbook.NewH1D(100, -5, 5)
 		h01 = hbook.NewH1D(100, -5, 5)
@@ -92,9 +99,6 @@ func tree1(fname string, evtmax int64) error {
 
 	for sc.Next() && sc.Entry() < evtmax {
 		iev := sc.Entry()
-		if iev%1000 == 0 {
-			log.Printf("processing event %d...", iev)
-		}
 
 		err = sc.Scan()
 		if err != nil {
@@ -123,6 +127,7 @@ func tree1(fname string, evtmax int64) error {
 	if err != nil {
 		return fmt.Errorf("could not read tree: %w", err)
 	}
+	rep.Close()
 
 	{
 		tp := hplot.NewTiledPlot(draw.Tiles{
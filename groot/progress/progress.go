@@ -0,0 +1,270 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package progress reports the progress of scans over the entries of ROOT
+// trees: the number of processed entries, the throughput (in entries/s and
+// MB/s), the percentage of processed entries and the estimated remaining
+// time.
+//
+// On a terminal, the reporter refreshes a single status line in place.
+// Otherwise, it writes a status line periodically.
+package progress // import "github.com/go-hep/examples/groot/progress"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+const (
+	// DefaultInterval is the default interval between two status lines,
+	// when not writing to a terminal.
+	DefaultInterval = 10 * time.Second
+
+	// refresh is the interval between two refreshes of the status line
+	// on a terminal.
+	refresh = 250 * time.Millisecond
+)
+
+// Reporter reports the progress of a scan over the entries of trees.
+// Reporter methods are safe for concurrent use, and are no-ops on a nil
+// reporter.
+type Reporter struct {
+	n    int64 // number of processed entries (accessed atomically)
+	skip int64 // number of entries processed before the scan (accessed atomically)
+
+	w        io.Writer
+	name     string
+	total    int64        // number of entries to process (<= 0: unknown)
+	bytes    func() int64 // number of bytes read so far, if known
+	tty      bool
+	interval time.Duration
+
+	beg    time.Time
+	bytes0 int64
+
+	once sync.Once
+	quit chan struct{}
+	done chan struct{}
+}
+
+// Option configures a Reporter.
+type Option func(r *Reporter)
+
+// WithName prefixes the status lines with the provided name.
+func WithName(name string) Option {
+	return func(r *Reporter) {
+		r.name = name
+	}
+}
+
+// WithBytes sets the function returning the number of bytes read so far,
+// used to report the throughput in MB/s.
+func WithBytes(fct func() int64) Option {
+	return func(r *Reporter) {
+		r.bytes = fct
+	}
+}
+
+// WithInterval sets the interval between two status lines, when not
+// writing to a terminal.
+func WithInterval(d time.Duration) Option {
+	return func(r *Reporter) {
+		r.interval = d
+	}
+}
+
+// WithTerminal forces (or prevents) the refresh of a single status line,
+// regardless of whether the reporter writes to a terminal.
+func WithTerminal(tty bool) Option {
+	return func(r *Reporter) {
+		r.tty = tty
+	}
+}
+
+// New creates a new reporter of the progress of a scan over total entries
+// (an unknown number of entries if total <= 0), writing to w.
+// The reporter starts reporting right away, until Close is called.
+func New(w io.Writer, total int64, opts ...Option) *Reporter {
+	r := &Reporter{
+		w:        w,
+		total:    total,
+		tty:      isTerminal(w),
+		interval: DefaultInterval,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.bytes != nil {
+		r.bytes0 = r.bytes()
+	}
+	r.beg = time.Now()
+
+	interval := r.interval
+	if r.tty {
+		interval = refresh
+	}
+	go r.run(interval)
+
+	return r
+}
+
+func (r *Reporter) run(interval time.Duration) {
+	defer close(r.done)
+
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-r.quit:
+			return
+		case now := <-tick.C:
+			r.write(r.status(now, false))
+		}
+	}
+}
+
+// Add records n more processed entries.
+func (r *Reporter) Add(n int64) {
+	if r == nil {
+		return
+	}
+	atomic.AddInt64(&r.n, n)
+}
+
+// Skip records n entries processed before the scan (e.g. by a previous,
+// interrupted, scan): they count towards the percentage of processed
+// entries, but not towards the throughput.
+func (r *Reporter) Skip(n int64) {
+	if r == nil {
+		return
+	}
+	atomic.AddInt64(&r.skip, n)
+}
+
+// Close stops reporting, and writes the final status line.
+func (r *Reporter) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.once.Do(func() {
+		close(r.quit)
+		<-r.done
+		r.write(r.status(time.Now(), true))
+		if r.tty {
+			fmt.Fprintln(r.w)
+		}
+	})
+	return nil
+}
+
+func (r *Reporter) write(line string) {
+	switch {
+	case r.tty:
+		// rewrite the current line and clear what remains of the previous one.
+		fmt.Fprintf(r.w, "\r%s\x1b[K", line)
+	default:
+		fmt.Fprintln(r.w, line)
+	}
+}
+
+// status returns the status line of the scan at the provided time.
+func (r *Reporter) status(now time.Time, final bool) string {
+	var (
+		n     = atomic.LoadInt64(&r.n)
+		skip  = atomic.LoadInt64(&r.skip)
+		delta = now.Sub(r.beg)
+		secs  = delta.Seconds()
+		o     = new(strings.Builder)
+	)
+	if r.name != "" {
+		fmt.Fprintf(o, "%s: ", r.name)
+	}
+
+	switch {
+	case r.total > 0:
+		fmt.Fprintf(o, "%d/%d entries (%.1f%%)", skip+n, r.total, 100*float64(skip+n)/float64(r.total))
+	default:
+		fmt.Fprintf(o, "%d entries", skip+n)
+	}
+
+	if secs > 0 {
+		fmt.Fprintf(o, ", %.0f entries/s", float64(n)/secs)
+		if r.bytes != nil {
+			fmt.Fprintf(o, ", %.1f MB/s", float64(r.bytes()-r.bytes0)/secs/1e6)
+		}
+	}
+
+	switch {
+	case final:
+		fmt.Fprintf(o, ", done in %v", delta.Round(time.Millisecond))
+	case r.total > 0 && n > 0:
+		left := r.total - skip - n
+		if left < 0 {
+			left = 0
+		}
+		eta := time.Duration(float64(left) / float64(n) * float64(delta))
+		fmt.Fprintf(o, ", ETA %v", eta.Round(time.Second))
+	}
+
+	return o.String()
+}
+
+// isTerminal returns whether w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of the parent context carrying the provided
+// reporter.
+func NewContext(ctx context.Context, r *Reporter) context.Context {
+	return context.WithValue(ctx, ctxKey{}, r)
+}
+
+// FromContext returns the reporter carried by the context, or nil.
+func FromContext(ctx context.Context) *Reporter {
+	r, _ := ctx.Value(ctxKey{}).(*Reporter)
+	return r
+}
+
+// Scanner is a tree scanner reporting each scanned entry.
+type Scanner struct {
+	*rtree.Scanner
+	r *Reporter
+}
+
+// NewScanner returns a scanner reporting each entry scanned by sc to r.
+func NewScanner(sc *rtree.Scanner, r *Reporter) *Scanner {
+	return &Scanner{Scanner: sc, r: r}
+}
+
+// Scan loads the current entry into the bound variables, and reports it.
+func (sc *Scanner) Scan() error {
+	err := sc.Scanner.Scan()
+	if err != nil {
+		return err
+	}
+	sc.r.Add(1)
+	return nil
+}
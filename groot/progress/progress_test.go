@@ -0,0 +1,261 @@
+// Copyright 2020 The go-hep Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package progress
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-hep.org/x/hep/groot"
+	"go-hep.org/x/hep/groot/rtree"
+)
+
+func TestStatus(t *testing.T) {
+	var (
+		bytes = int64(1e6)
+		w     = new(strings.Builder)
+	)
+
+	for _, tc := range []struct {
+		name  string
+		total int64
+		opts  []Option
+		skip  int64
+		final bool
+		want  string
+	}{
+		{
+			name:  "eta",
+			total: 2000,
+			opts:  []Option{WithName("scan")},
+			want:  "scan: 500/2000 entries (25.0%), 250 entries/s, ETA 6s",
+		},
+		{
+			name:  "bytes",
+			total: 2000,
+			opts: []Option{WithBytes(func() int64 {
+				bytes += 1e6
+				return bytes
+			})},
+			want: "500/2000 entries (25.0%), 250 entries/s, 0.5 MB/s, ETA 6s",
+		},
+		{
+			name:  "skip",
+			total: 2000,
+			skip:  1000,
+			want:  "1500/2000 entries (75.0%), 250 entries/s, ETA 2s",
+		},
+		{
+			name: "unknown",
+			want: "500 entries, 250 entries/s",
+		},
+		{
+			name:  "final",
+			total: 2000,
+			final: true,
+			want:  "500/2000 entries (25.0%), 250 entries/s, done in 2s",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New(w, tc.total, append(tc.opts, WithInterval(time.Hour))...)
+			defer r.Close()
+
+			r.Skip(tc.skip)
+			r.Add(200)
+			r.Add(300)
+
+			got := r.status(r.beg.Add(2*time.Second), tc.final)
+			if got != tc.want {
+				t.Fatalf("invalid status:\ngot= %q\nwant=%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReporter(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tty  bool
+		want func(out string) bool
+	}{
+		{
+			name: "log",
+			tty:  false,
+			want: func(out string) bool {
+				lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+				if len(lines) < 2 {
+					return false
+				}
+				return strings.HasPrefix(lines[len(lines)-1], "test: 400/400 entries (100.0%)")
+			},
+		},
+		{
+			name: "tty",
+			tty:  true,
+			want: func(out string) bool {
+				return strings.Count(out, "\n") == 1 &&
+					strings.HasSuffix(out, "\x1b[K\n") &&
+					strings.Contains(out, "\rtest: 400/400 entries (100.0%)")
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				w  = new(syncBuilder)
+				r  = New(w, 400, WithName("test"), WithInterval(time.Millisecond), WithTerminal(tc.tty))
+				wg sync.WaitGroup
+			)
+
+			wg.Add(4)
+			for i := 0; i < 4; i++ {
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						r.Add(1)
+						time.Sleep(50 * time.Microsecond)
+					}
+				}()
+			}
+			wg.Wait()
+			time.Sleep(10 * time.Millisecond)
+
+			r.Close()
+			r.Close()
+
+			if out := w.String(); !tc.want(out) {
+				t.Fatalf("invalid output:\n%q", out)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if r := FromContext(ctx); r != nil {
+		t.Fatalf("unexpected reporter: %v", r)
+	}
+
+	// nil reporters are no-ops.
+	var r *Reporter
+	r.Add(1)
+	r.Skip(1)
+	r.Close()
+
+	r = New(ioutil.Discard, 0)
+	defer r.Close()
+
+	if got := FromContext(NewContext(ctx, r)); got != r {
+		t.Fatalf("invalid reporter: got=%p, want=%p", got, r)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "progress-")
+	if err != nil {
+		t.Fatalf("could not create tmp dir: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	const nevts = 100
+	fname := filepath.Join(dir, "tree.root")
+	err = createTree(fname, nevts)
+	if err != nil {
+		t.Fatalf("could not create tree: %+v", err)
+	}
+
+	f, err := groot.Open(fname)
+	if err != nil {
+		t.Fatalf("could not open file: %+v", err)
+	}
+	defer f.Close()
+
+	obj, err := f.Get("tree")
+	if err != nil {
+		t.Fatalf("could not retrieve tree: %+v", err)
+	}
+	tree := obj.(rtree.Tree)
+
+	var v float64
+	rsc, err := rtree.NewScannerVars(tree, rtree.ScanVar{Name: "v", Value: &v})
+	if err != nil {
+		t.Fatalf("could not create scanner: %+v", err)
+	}
+	defer rsc.Close()
+
+	r := New(ioutil.Discard, tree.Entries(), WithInterval(time.Hour))
+	defer r.Close()
+
+	sc := NewScanner(rsc, r)
+	for sc.Next() {
+		if sc.Entry()%2 == 0 {
+			continue
+		}
+		err := sc.Scan()
+		if err != nil {
+			t.Fatalf("could not scan entry %d: %+v", sc.Entry(), err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("could not scan tree: %+v", err)
+	}
+
+	if got, want := r.n, int64(nevts/2); got != want {
+		t.Fatalf("invalid number of reported entries: got=%d, want=%d", got, want)
+	}
+}
+
+func createTree(fname string, nevts int) error {
+	f, err := groot.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var v float64
+	w, err := rtree.NewWriter(f, "tree", []rtree.WriteVar{{Name: "v", Value: &v}})
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for i := 0; i < nevts; i++ {
+		v = float64(i)
+		_, err = w.Write()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// syncBuilder is a strings.Builder safe for concurrent use.
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (w *syncBuilder) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.Write(p)
+}
+
+func (w *syncBuilder) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.b.String()
+}